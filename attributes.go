@@ -0,0 +1,120 @@
+package engineclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MarshalJSON encodes the timestamp as a hex quantity of unix seconds. It
+// omits withdrawals only when they are nil, so pre-Shanghai attributes stay
+// valid for forkchoiceUpdatedV1 while Shanghai attributes without withdrawals
+// still encode an empty list.
+func (a PayloadAttributes) MarshalJSON() ([]byte, error) {
+	if a.Timestamp.Unix() < 0 {
+		return nil, fmt.Errorf("timestamp %v is before the unix epoch", a.Timestamp)
+	}
+	type attributes PayloadAttributes
+	enc := struct {
+		Timestamp string `json:"timestamp"`
+		attributes
+		Withdrawals *[]Withdrawal `json:"withdrawals,omitempty"`
+	}{
+		Timestamp:  fmt.Sprintf("0x%x", a.Timestamp.Unix()),
+		attributes: attributes(a),
+	}
+	if a.Withdrawals != nil {
+		enc.Withdrawals = &a.Withdrawals
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes the hex timestamp into a time.Time
+func (a *PayloadAttributes) UnmarshalJSON(data []byte) error {
+	type attributes PayloadAttributes
+	dec := struct {
+		*attributes
+		Timestamp string `json:"timestamp"`
+	}{attributes: (*attributes)(a)}
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	seconds, err := decodeQuantity(dec.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %v", err)
+	}
+	a.Timestamp = time.Unix(int64(seconds), 0)
+	return nil
+}
+
+// PayloadAttributesBuilder assembles PayloadAttributes from native Go values
+type PayloadAttributesBuilder struct {
+	timestamp             time.Time
+	prevRandao            Hash
+	feeRecipient          Address
+	withdrawals           []Withdrawal
+	parentBeaconBlockRoot *Hash
+}
+
+// NewPayloadAttributes starts building payload attributes. Unset randao and
+// fee recipient default to zero values; a timestamp is required.
+func NewPayloadAttributes() *PayloadAttributesBuilder {
+	return &PayloadAttributesBuilder{}
+}
+
+// WithTimestamp sets the timestamp of the payload to build
+func (b *PayloadAttributesBuilder) WithTimestamp(t time.Time) *PayloadAttributesBuilder {
+	b.timestamp = t
+	return b
+}
+
+// WithRandao sets prevRandao
+func (b *PayloadAttributesBuilder) WithRandao(randao Hash) *PayloadAttributesBuilder {
+	b.prevRandao = randao
+	return b
+}
+
+// WithFeeRecipient sets the suggested fee recipient
+func (b *PayloadAttributesBuilder) WithFeeRecipient(addr Address) *PayloadAttributesBuilder {
+	b.feeRecipient = addr
+	return b
+}
+
+// WithWithdrawals sets the withdrawals to include, making the attributes
+// Shanghai attributes even when called with no withdrawals
+func (b *PayloadAttributesBuilder) WithWithdrawals(withdrawals ...Withdrawal) *PayloadAttributesBuilder {
+	b.withdrawals = append([]Withdrawal{}, withdrawals...)
+	return b
+}
+
+// WithParentBeaconBlockRoot sets the Cancun parent beacon block root
+func (b *PayloadAttributesBuilder) WithParentBeaconBlockRoot(root Hash) *PayloadAttributesBuilder {
+	b.parentBeaconBlockRoot = &root
+	return b
+}
+
+// Build validates the collected values and returns the attributes
+func (b *PayloadAttributesBuilder) Build() (*PayloadAttributes, error) {
+	if b.timestamp.IsZero() {
+		return nil, fmt.Errorf("payload attributes require a timestamp")
+	}
+	if b.timestamp.Unix() < 0 {
+		return nil, fmt.Errorf("timestamp %v is before the unix epoch", b.timestamp)
+	}
+	if b.parentBeaconBlockRoot != nil && b.withdrawals == nil {
+		return nil, fmt.Errorf("parent beacon block root requires withdrawals to be set")
+	}
+	for i, w := range b.withdrawals {
+		if _, err := encodeWithdrawal(w); err != nil {
+			return nil, fmt.Errorf("withdrawal %d: %v", i, err)
+		}
+	}
+
+	return &PayloadAttributes{
+		Timestamp:             b.timestamp,
+		PrevRandao:            b.prevRandao,
+		SuggestedFeeRecipient: b.feeRecipient,
+		Withdrawals:           b.withdrawals,
+		ParentBeaconBlockRoot: b.parentBeaconBlockRoot,
+	}, nil
+}
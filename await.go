@@ -0,0 +1,45 @@
+package engineclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	awaitInitialBackoff = 100 * time.Millisecond
+	awaitMaxBackoff     = 2 * time.Second
+)
+
+// NewPayloadAndWait submits the payload and, while the EL answers SYNCING or
+// ACCEPTED, re-submits it with exponential backoff until it returns VALID,
+// INVALID or INVALID_BLOCK_HASH. The context deadline bounds the wait; on
+// expiry the last non-final status is returned together with the error.
+func (c *EngineClient) NewPayloadAndWait(ctx context.Context, payload map[string]interface{}) (*PayloadStatus, error) {
+	backoff := awaitInitialBackoff
+	for {
+		response, err := c.NewPayload(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		var status PayloadStatus
+		if err := decodeResult(response, &status); err != nil {
+			return nil, err
+		}
+		if status.Final() {
+			return &status, nil
+		}
+
+		if !c.sleep(ctx, backoff) {
+			err := fmt.Errorf("payload still %s: %w", status.Status, wrapTimeout(ctx.Err()))
+			if status.Status == StatusSyncing {
+				err = fmt.Errorf("%w: %w", ErrELSyncing, err)
+			}
+			return &status, err
+		}
+		backoff *= 2
+		if backoff > awaitMaxBackoff {
+			backoff = awaitMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package engineclient
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// endpointFailureThreshold consecutive failures take a read endpoint
+	// out of rotation for endpointCooldown
+	endpointFailureThreshold = 3
+	endpointCooldown         = 10 * time.Second
+)
+
+// readMethods are the eth_ methods served identically by any synced EL, and
+// so safe to spread across endpoints
+var readMethods = map[string]bool{
+	"eth_chainId":               true,
+	"eth_getBlockByHash":        true,
+	"eth_getBlockByNumber":      true,
+	"eth_getBlockReceipts":      true,
+	"eth_getTransactionByHash":  true,
+	"eth_getTransactionReceipt": true,
+	"eth_getLogs":               true,
+	"eth_call":                  true,
+	"eth_getBalance":            true,
+	"eth_getCode":               true,
+	"eth_getStorageAt":          true,
+	"eth_getProof":              true,
+}
+
+// isReadMethod reports whether a call is stateless and may be load
+// balanced. Every other call, including all stateful engine methods, goes to
+// the primary endpoint.
+func isReadMethod(method string) bool {
+	return readMethods[method] || strings.HasPrefix(method, "engine_getPayloadBodiesBy")
+}
+
+// EndpointStatus is the health of one load-balanced endpoint
+type EndpointStatus struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"`
+	DownUntil time.Time `json:"downUntil,omitempty"`
+}
+
+type balancedEndpoint struct {
+	url       string
+	failures  int
+	downUntil time.Time
+}
+
+// loadBalancer rotates read calls across endpoints, skipping those that
+// have recently failed repeatedly
+type loadBalancer struct {
+	mu        sync.Mutex
+	endpoints []*balancedEndpoint
+	next      int
+}
+
+func newLoadBalancer(urls []string) *loadBalancer {
+	lb := &loadBalancer{}
+	for _, u := range urls {
+		lb.endpoints = append(lb.endpoints, &balancedEndpoint{url: u})
+	}
+	return lb
+}
+
+// pick returns the next healthy endpoint in rotation, or the one that comes
+// back soonest if none are healthy
+func (lb *loadBalancer) pick(now time.Time) string {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	var soonest *balancedEndpoint
+	for i := 0; i < len(lb.endpoints); i++ {
+		e := lb.endpoints[(lb.next+i)%len(lb.endpoints)]
+		if !now.Before(e.downUntil) {
+			lb.next = (lb.next + i + 1) % len(lb.endpoints)
+			return e.url
+		}
+		if soonest == nil || e.downUntil.Before(soonest.downUntil) {
+			soonest = e
+		}
+	}
+	return soonest.url
+}
+
+// report records the outcome of a call. Only transport failures and 5xx
+// replies count against an endpoint; RPC errors mean it is up.
+func (lb *loadBalancer) report(url string, err error, now time.Time) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	for _, e := range lb.endpoints {
+		if e.url != url {
+			continue
+		}
+		if !endpointFailed(err) {
+			e.failures, e.downUntil = 0, time.Time{}
+			return
+		}
+		e.failures++
+		if e.failures >= endpointFailureThreshold {
+			e.downUntil = now.Add(endpointCooldown)
+		}
+		return
+	}
+}
+
+func endpointFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	var schemaErr *SchemaViolationError
+	return !errors.As(err, &schemaErr)
+}
+
+func (lb *loadBalancer) status(now time.Time) []EndpointStatus {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	out := make([]EndpointStatus, len(lb.endpoints))
+	for i, e := range lb.endpoints {
+		out[i] = EndpointStatus{URL: e.url, Healthy: !now.Before(e.downUntil), Failures: e.failures}
+		if !out[i].Healthy {
+			out[i].DownUntil = e.downUntil
+		}
+	}
+	return out
+}
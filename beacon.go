@@ -0,0 +1,368 @@
+package engineclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BeaconClient reads payload attributes and proposer duties from a consensus
+// client's beacon REST API, so payloads can be built from the real chain
+// rather than made-up attributes
+type BeaconClient struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewBeaconClient creates a client for the beacon API at endpoint. Requests
+// have no timeout of their own since the event stream is long lived; callers
+// bound them through the context.
+func NewBeaconClient(endpoint string) *BeaconClient {
+	return &BeaconClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   &http.Client{},
+	}
+}
+
+// beaconWithdrawal is a withdrawal as the beacon API encodes it, with decimal
+// quantities
+type beaconWithdrawal struct {
+	Index          string  `json:"index"`
+	ValidatorIndex string  `json:"validator_index"`
+	Address        Address `json:"address"`
+	Amount         string  `json:"amount"`
+}
+
+// PayloadAttributesEvent is the data of a payload_attributes event, emitted
+// by the beacon node ahead of every slot it expects a payload to be built for
+type PayloadAttributesEvent struct {
+	Version           string `json:"-"`
+	ProposerIndex     string `json:"proposer_index"`
+	ProposalSlot      string `json:"proposal_slot"`
+	ParentBlockNumber string `json:"parent_block_number"`
+	ParentBlockRoot   Hash   `json:"parent_block_root"`
+	ParentBlockHash   Hash   `json:"parent_block_hash"`
+	PayloadAttributes struct {
+		Timestamp             string             `json:"timestamp"`
+		PrevRandao            Hash               `json:"prev_randao"`
+		SuggestedFeeRecipient Address            `json:"suggested_fee_recipient"`
+		Withdrawals           []beaconWithdrawal `json:"withdrawals"`
+		ParentBeaconBlockRoot *Hash              `json:"parent_beacon_block_root"`
+	} `json:"payload_attributes"`
+}
+
+// EngineAttributes converts the event into engine API payload attributes.
+// Withdrawals and the parent beacon block root are carried over only for the
+// forks that define them.
+func (e *PayloadAttributesEvent) EngineAttributes() (*PayloadAttributes, error) {
+	attrs := e.PayloadAttributes
+	timestamp, err := strconv.ParseUint(attrs.Timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q: %v", attrs.Timestamp, err)
+	}
+	b := NewPayloadAttributes().
+		WithTimestamp(time.Unix(int64(timestamp), 0)).
+		WithRandao(attrs.PrevRandao).
+		WithFeeRecipient(attrs.SuggestedFeeRecipient)
+
+	if e.Version != "bellatrix" {
+		withdrawals := make([]Withdrawal, len(attrs.Withdrawals))
+		for i, w := range attrs.Withdrawals {
+			out := Withdrawal{Address: w.Address}
+			for _, f := range []struct {
+				name string
+				in   string
+				out  *string
+			}{
+				{"index", w.Index, &out.Index},
+				{"validator_index", w.ValidatorIndex, &out.ValidatorIndex},
+				{"amount", w.Amount, &out.Amount},
+			} {
+				v, err := strconv.ParseUint(f.in, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("withdrawal %d: invalid %s %q", i, f.name, f.in)
+				}
+				*f.out = "0x" + strconv.FormatUint(v, 16)
+			}
+			withdrawals[i] = out
+		}
+		b.WithWithdrawals(withdrawals...)
+	}
+	if attrs.ParentBeaconBlockRoot != nil {
+		b.WithParentBeaconBlockRoot(*attrs.ParentBeaconBlockRoot)
+	}
+	return b.Build()
+}
+
+// SubscribePayloadAttributes streams payload_attributes events from
+// /eth/v1/events, calling handler for each one until ctx is done or the
+// stream fails
+func (b *BeaconClient) SubscribePayloadAttributes(ctx context.Context, handler func(*PayloadAttributesEvent)) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.endpoint+"/eth/v1/events?topics=payload_attributes", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var event string
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event == "payload_attributes" && data.Len() > 0 {
+				var envelope struct {
+					Version string                 `json:"version"`
+					Data    PayloadAttributesEvent `json:"data"`
+				}
+				if err := json.Unmarshal(data.Bytes(), &envelope); err != nil {
+					return fmt.Errorf("failed to decode payload_attributes event: %v", err)
+				}
+				envelope.Data.Version = envelope.Version
+				handler(&envelope.Data)
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("event stream failed: %v", err)
+	}
+	return fmt.Errorf("event stream closed by beacon node")
+}
+
+// ProposerDuty assigns a slot to the validator expected to propose it
+type ProposerDuty struct {
+	Pubkey         string `json:"pubkey"`
+	ValidatorIndex string `json:"validator_index"`
+	Slot           string `json:"slot"`
+}
+
+// ProposerDuties returns the proposers for every slot of epoch
+func (b *BeaconClient) ProposerDuties(ctx context.Context, epoch uint64) ([]ProposerDuty, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/eth/v1/validator/duties/proposer/%d", b.endpoint, epoch), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch proposer duties: %w", wrapTimeout(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+	var body struct {
+		Data []ProposerDuty `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode proposer duties: %v", err)
+	}
+	return body.Data, nil
+}
+
+// attributesFork picks the fork whose forkchoiceUpdated and getPayload
+// versions accept attributes of this shape
+func attributesFork(attributes *PayloadAttributes) Fork {
+	switch {
+	case attributes.ParentBeaconBlockRoot != nil:
+		return ForkCancun
+	case attributes.Withdrawals != nil:
+		return ForkShanghai
+	default:
+		return ForkParis
+	}
+}
+
+// runShadow builds a payload for every slot the beacon node announces, using
+// its real attributes, and reports what the EL produced without proposing it
+func runShadow(args []string) error {
+	fs := flag.NewFlagSet("shadow", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	beaconURL := fs.String("beacon", "http://localhost:5052", "beacon node REST API")
+	feeRecipient := fs.String("fee-recipient", "", "override the suggested fee recipient")
+	validators := fs.String("validators", "", "comma-separated validator indices; only build for slots they propose")
+	slotsPerEpoch := fs.Uint64("slots-per-epoch", 32, "slots per epoch, for proposer duty lookups")
+	buildTime := fs.Duration("build-time", 4*time.Second, "time to let the EL build before fetching the payload")
+	statusAddr := fs.String("status-addr", "", "serve /healthz, /readyz and /status on this address")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	var recipient *Address
+	if *feeRecipient != "" {
+		addr, err := HexToAddress(*feeRecipient)
+		if err != nil {
+			return fmt.Errorf("invalid -fee-recipient: %v", err)
+		}
+		recipient = &addr
+	}
+	watched := make(map[string]bool)
+	for _, v := range strings.Split(*validators, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			watched[v] = true
+		}
+	}
+
+	values := NewBlockValueTracker(256)
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient(WithBlockValueTracker(values))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	startStatusServer(*statusAddr, client, client.fork)
+	beacon := NewBeaconClient(*beaconURL)
+	ctx := context.Background()
+
+	// Proposers are looked up per epoch so duties can be logged alongside
+	// the slot even when no validator filter is set.
+	duties := make(map[uint64]map[string]string)
+	proposerOf := func(slot uint64) string {
+		epoch := slot / *slotsPerEpoch
+		if _, ok := duties[epoch]; !ok {
+			list, err := beacon.ProposerDuties(ctx, epoch)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "epoch %d: %v\n", epoch, err)
+				return ""
+			}
+			duties[epoch] = make(map[string]string, len(list))
+			for _, d := range list {
+				duties[epoch][d.Slot] = d.ValidatorIndex
+			}
+		}
+		return duties[epoch][strconv.FormatUint(slot, 10)]
+	}
+
+	// report prints the outcome of one slot; p is nil when the build failed
+	// and value is nil for Paris payloads, which carry none
+	report := func(slot uint64, proposer string, p *ExecutionPayload, value *Wei, err error) {
+		rec := record{
+			{"slot", slot}, {"proposer", proposer}, {"block", nil}, {"hash", nil},
+			{"txs", nil}, {"withdrawals", nil}, {"valueEth", nil}, {"error", err},
+		}
+		if p != nil {
+			rec[2].value, rec[3].value = p.BlockNumber, p.BlockHash
+			rec[4].value, rec[5].value = len(p.Transactions), len(p.Withdrawals)
+			if value != nil {
+				rec[6].value = value.Ether()
+			}
+		}
+		out.emit(rec, func(w io.Writer) {
+			if err != nil {
+				fmt.Fprintf(w, "slot %d proposer %s: %v\n", slot, proposer, err)
+				return
+			}
+			ether := "0"
+			if value != nil {
+				ether = value.Ether()
+			}
+			fmt.Fprintf(w, "slot %d proposer %s: block %s %s txs=%d withdrawals=%d value=%s ETH\n",
+				slot, proposer, p.BlockNumber, p.BlockHash, len(p.Transactions), len(p.Withdrawals), ether)
+			if value != nil {
+				fmt.Fprintf(w, "  values: %s\n", values.Summary(time.Time{}))
+			}
+		})
+	}
+
+	return beacon.SubscribePayloadAttributes(ctx, func(event *PayloadAttributesEvent) {
+		slot, err := strconv.ParseUint(event.ProposalSlot, 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid proposal slot %q\n", event.ProposalSlot)
+			return
+		}
+		proposer := event.ProposerIndex
+		if duty := proposerOf(slot); duty != "" {
+			proposer = duty
+		}
+		if len(watched) > 0 && !watched[proposer] {
+			return
+		}
+
+		attributes, err := event.EngineAttributes()
+		if err != nil {
+			report(slot, proposer, nil, nil, err)
+			return
+		}
+		if recipient != nil {
+			attributes.SuggestedFeeRecipient = *recipient
+		}
+		// The beacon API exposes finality as block roots, not execution
+		// hashes, so safe and finalized are left unset.
+		state := ForkChoiceState{HeadBlockHash: event.ParentBlockHash}
+		fork := attributesFork(attributes)
+
+		response, err := client.CallMethod(ctx, FamilyForkchoiceUpdated, fork, MethodArgs{State: &state, Attributes: attributes})
+		var fcu ForkchoiceUpdatedResult
+		if err == nil {
+			err = decodeResult(response, &fcu)
+		}
+		if err == nil && fcu.PayloadID == nil {
+			err = missingPayloadIDError(fcu.PayloadStatus)
+		}
+		if err != nil {
+			report(slot, proposer, nil, nil, err)
+			return
+		}
+
+		if !client.sleep(ctx, *buildTime) {
+			report(slot, proposer, nil, nil, ctx.Err())
+			return
+		}
+		response, err = client.CallMethod(ctx, FamilyGetPayload, fork, MethodArgs{PayloadID: *fcu.PayloadID})
+		var envelope struct {
+			ExecutionPayload ExecutionPayload `json:"executionPayload"`
+			BlockValue       Wei              `json:"blockValue"`
+		}
+		if err == nil && fork == ForkParis {
+			err = decodeResult(response, &envelope.ExecutionPayload)
+		} else if err == nil {
+			err = decodeResult(response, &envelope)
+		}
+		if err != nil {
+			report(slot, proposer, nil, nil, err)
+			return
+		}
+		value := &envelope.BlockValue
+		if fork == ForkParis {
+			value = nil
+		}
+		report(slot, proposer, &envelope.ExecutionPayload, value, nil)
+	})
+}
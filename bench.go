@@ -0,0 +1,259 @@
+package engineclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadTestConfig controls how RunLoadTest replays requests
+type LoadTestConfig struct {
+	Method string
+	// Rate is the number of requests started per second; zero means as fast
+	// as the workers allow
+	Rate        float64
+	Concurrency int
+	// Requests stops the test after this many requests; zero means replay
+	// until Duration elapses or the context is done
+	Requests int
+	Duration time.Duration
+}
+
+// LoadTestReport summarises latency and outcomes of a load test
+type LoadTestReport struct {
+	Sent      int
+	Errors    int
+	Statuses  map[string]int
+	Elapsed   time.Duration
+	latencies []time.Duration
+}
+
+// Percentile returns the latency below which p percent of successful calls
+// completed
+func (r *LoadTestReport) Percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.latencies)-1) * p / 100)
+	return r.latencies[i]
+}
+
+// ErrorRate is the fraction of calls that failed at the transport or RPC level
+func (r *LoadTestReport) ErrorRate() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Sent)
+}
+
+func (r *LoadTestReport) record() record {
+	return record{
+		{"requests", r.Sent},
+		{"errors", r.Errors},
+		{"errorRate", r.ErrorRate()},
+		{"elapsed", r.Elapsed.Round(time.Millisecond)},
+		{"rate", float64(r.Sent) / r.Elapsed.Seconds()},
+		{"p50", r.Percentile(50)},
+		{"p90", r.Percentile(90)},
+		{"p99", r.Percentile(99)},
+		{"max", r.Percentile(100)},
+		{"statuses", r.Statuses},
+	}
+}
+
+func (r *LoadTestReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "requests: %d in %s (%.1f req/s)\n", r.Sent, r.Elapsed.Round(time.Millisecond), float64(r.Sent)/r.Elapsed.Seconds())
+	fmt.Fprintf(&sb, "errors:   %d (%.2f%%)\n", r.Errors, 100*r.ErrorRate())
+	fmt.Fprintf(&sb, "latency:  p50=%s p90=%s p99=%s max=%s\n",
+		r.Percentile(50), r.Percentile(90), r.Percentile(99), r.Percentile(100))
+	statuses := make([]string, 0, len(r.Statuses))
+	for status := range r.Statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(&sb, "status %s: %d\n", status, r.Statuses[status])
+	}
+	return sb.String()
+}
+
+// RunLoadTest cycles through params, sending each as one call to cfg.Method
+// at the configured rate and concurrency. Pacing and latencies follow the
+// client's clock.
+func RunLoadTest(ctx context.Context, client *EngineClient, params [][]interface{}, cfg LoadTestConfig) (*LoadTestReport, error) {
+	if len(params) == 0 {
+		return nil, fmt.Errorf("no requests to replay")
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Requests == 0 && cfg.Duration == 0 {
+		cfg.Requests = len(params)
+	}
+	// Duration only stops dispatching; calls already in flight run to
+	// completion under the caller's context.
+	dispatchCtx := ctx
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		dispatchCtx, cancel = client.withTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	jobs := make(chan []interface{})
+	go func() {
+		defer close(jobs)
+		interval := time.Duration(0)
+		if cfg.Rate > 0 {
+			interval = time.Duration(float64(time.Second) / cfg.Rate)
+		}
+		next := client.clock.Now()
+		for i := 0; cfg.Requests == 0 || i < cfg.Requests; i++ {
+			if interval > 0 {
+				// Requests are started on a fixed schedule, so a slow
+				// dispatch is caught up rather than shifting the rest.
+				next = next.Add(interval)
+				if !client.sleep(dispatchCtx, next.Sub(client.clock.Now())) {
+					return
+				}
+			}
+			select {
+			case jobs <- params[i%len(params)]:
+			case <-dispatchCtx.Done():
+				return
+			}
+		}
+	}()
+
+	report := &LoadTestReport{Statuses: make(map[string]int)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := client.clock.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				callStart := client.clock.Now()
+				response, err := client.Call(ctx, cfg.Method, p)
+				latency := client.clock.Now().Sub(callStart)
+
+				var status PayloadStatus
+				if err == nil {
+					err = decodeResult(response, &status)
+				}
+				mu.Lock()
+				report.Sent++
+				if err != nil {
+					report.Errors++
+				} else {
+					report.latencies = append(report.latencies, latency)
+					if status.Status != "" {
+						report.Statuses[status.Status]++
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	report.Elapsed = client.clock.Now().Sub(start)
+	sort.Slice(report.latencies, func(i, j int) bool { return report.latencies[i] < report.latencies[j] })
+	return report, nil
+}
+
+// loadParamsFile reads one request per line: a JSON array is used as the
+// full params list, anything else as the single parameter
+func loadParamsFile(path string) ([][]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	var out [][]interface{}
+	for i, line := range readLines(bytes.NewReader(data)) {
+		var value interface{}
+		if err := json.Unmarshal([]byte(line), &value); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+		}
+		if params, ok := value.([]interface{}); ok {
+			out = append(out, params)
+		} else {
+			out = append(out, []interface{}{value})
+		}
+	}
+	return out, nil
+}
+
+// runBench replays a file of payloads against the EL and prints a report
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	file := fs.String("file", "", "JSONL file with one payload (or params array) per line")
+	method := fs.String("method", "", "method to call for each line (defaults to the newPayload version of -fork)")
+	forkName := fs.String("fork", "", "fork whose newPayload version -method defaults to (defaults to the configured fork, or paris)")
+	rate := fs.Float64("rate", 0, "requests per second (0 for unlimited)")
+	concurrency := fs.Int("concurrency", 4, "number of parallel workers")
+	requests := fs.Int("requests", 0, "total requests to send (0 to replay the file once, or until -duration)")
+	duration := fs.Duration("duration", 0, "stop after this long")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	params, err := loadParamsFile(*file)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if *method == "" {
+		fork := client.fork
+		if *forkName != "" {
+			if fork, err = ParseFork(*forkName); err != nil {
+				return err
+			}
+		}
+		spec, err := client.methods.Resolve(FamilyNewPayload, fork)
+		if err != nil {
+			return err
+		}
+		*method = spec.Name
+	}
+
+	report, err := RunLoadTest(context.Background(), client, params, LoadTestConfig{
+		Method:      *method,
+		Rate:        *rate,
+		Concurrency: *concurrency,
+		Requests:    *requests,
+		Duration:    *duration,
+	})
+	if err != nil {
+		return err
+	}
+	out.emit(report.record(), func(w io.Writer) { fmt.Fprint(w, report) })
+	if n := report.Statuses[StatusInvalid] + report.Statuses[StatusInvalidBlockHash]; n > 0 {
+		return &exitCodeError{code: exitInvalid, err: fmt.Errorf("%d payloads were INVALID", n)}
+	}
+	return nil
+}
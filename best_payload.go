@@ -0,0 +1,131 @@
+package engineclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PayloadBid is the outcome of asking a single EL to build a payload
+type PayloadBid struct {
+	Endpoint           string
+	PayloadID          string
+	BlockValue         *big.Int
+	Payload            map[string]interface{}
+	ForkchoiceDuration time.Duration
+	GetPayloadDuration time.Duration
+	Err                error
+}
+
+// BestPayloadResult holds the winning bid along with every EL's bid
+type BestPayloadResult struct {
+	Best *PayloadBid
+	Bids []*PayloadBid
+}
+
+// String renders a per-EL summary of timing and value
+func (r *BestPayloadResult) String() string {
+	var sb strings.Builder
+	for _, bid := range r.Bids {
+		marker := " "
+		if bid == r.Best {
+			marker = "*"
+		}
+		if bid.Err != nil {
+			fmt.Fprintf(&sb, "%s %s error=%v\n", marker, bid.Endpoint, bid.Err)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s %s value=%s ETH fcu=%s getPayload=%s\n",
+			marker, bid.Endpoint, FormatEther(bid.BlockValue), bid.ForkchoiceDuration, bid.GetPayloadDuration)
+	}
+	return sb.String()
+}
+
+// GetBestPayload asks every client to build a payload on top of the same
+// forkchoice state and attributes, waits buildTime, and returns the payload
+// with the highest blockValue
+func GetBestPayload(ctx context.Context, clients []*EngineClient, state ForkChoiceState, attributes *PayloadAttributes, buildTime time.Duration) (*BestPayloadResult, error) {
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("no clients provided")
+	}
+	if attributes == nil {
+		return nil, fmt.Errorf("payload attributes are required to start a build")
+	}
+
+	result := &BestPayloadResult{Bids: make([]*PayloadBid, len(clients))}
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client *EngineClient) {
+			defer wg.Done()
+			result.Bids[i] = client.requestBid(ctx, state, attributes, buildTime)
+		}(i, client)
+	}
+	wg.Wait()
+
+	for _, bid := range result.Bids {
+		if bid.Err != nil {
+			continue
+		}
+		if result.Best == nil || bid.BlockValue.Cmp(result.Best.BlockValue) > 0 {
+			result.Best = bid
+		}
+	}
+	if result.Best == nil {
+		return result, fmt.Errorf("no EL returned a payload")
+	}
+	return result, nil
+}
+
+func (c *EngineClient) requestBid(ctx context.Context, state ForkChoiceState, attributes *PayloadAttributes, buildTime time.Duration) *PayloadBid {
+	bid := &PayloadBid{Endpoint: c.endpoint}
+
+	start := c.clock.Now()
+	response, err := c.ForkchoiceUpdated(ctx, state, attributes)
+	bid.ForkchoiceDuration = c.clock.Now().Sub(start)
+	if err != nil {
+		bid.Err = err
+		return bid
+	}
+	var fcu ForkchoiceUpdatedResult
+	if err := decodeResult(response, &fcu); err != nil {
+		bid.Err = err
+		return bid
+	}
+	if fcu.PayloadID == nil {
+		bid.Err = missingPayloadIDError(fcu.PayloadStatus)
+		return bid
+	}
+	bid.PayloadID = *fcu.PayloadID
+
+	if !c.sleep(ctx, buildTime) {
+		bid.Err = ctx.Err()
+		return bid
+	}
+
+	start = c.clock.Now()
+	response, err = c.GetPayload(ctx, bid.PayloadID)
+	bid.GetPayloadDuration = c.clock.Now().Sub(start)
+	if err != nil {
+		bid.Err = err
+		return bid
+	}
+	var envelope struct {
+		ExecutionPayload map[string]interface{} `json:"executionPayload"`
+		BlockValue       *Wei                   `json:"blockValue"`
+	}
+	if err := decodeResult(response, &envelope); err != nil {
+		bid.Err = err
+		return bid
+	}
+	if envelope.BlockValue == nil {
+		bid.Err = fmt.Errorf("getPayload response has no blockValue")
+		return bid
+	}
+	bid.BlockValue = &envelope.BlockValue.Int
+	bid.Payload = envelope.ExecutionPayload
+	return bid
+}
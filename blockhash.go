@@ -0,0 +1,159 @@
+package engineclient
+
+import "fmt"
+
+// emptyUncleHash is the keccak256 hash of an RLP-encoded empty list
+var emptyUncleHash = MustHexToHash("0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347")
+
+// ComputeBlockHash rebuilds the execution block header from the payload fields
+// and returns its keccak256 hash. parentBeaconBlockRoot and requestsHash are
+// not part of the payload itself and must be supplied for Cancun and Prague
+// payloads respectively.
+func ComputeBlockHash(p *ExecutionPayload, parentBeaconBlockRoot, requestsHash *Hash) (Hash, error) {
+	header, err := encodeHeader(p, parentBeaconBlockRoot, requestsHash)
+	if err != nil {
+		return Hash{}, err
+	}
+	return Hash(keccak256(header)), nil
+}
+
+// VerifyBlockHash checks that the payload's blockHash matches the hash of the
+// header reconstructed from its fields
+func VerifyBlockHash(p *ExecutionPayload, parentBeaconBlockRoot, requestsHash *Hash) error {
+	computed, err := ComputeBlockHash(p, parentBeaconBlockRoot, requestsHash)
+	if err != nil {
+		return err
+	}
+	if computed != p.BlockHash {
+		return fmt.Errorf("block hash mismatch: payload has %s, computed %s", p.BlockHash, computed)
+	}
+	return nil
+}
+
+func encodeHeader(p *ExecutionPayload, parentBeaconBlockRoot, requestsHash *Hash) ([]byte, error) {
+	txs := make([][]byte, len(p.Transactions))
+	for i, tx := range p.Transactions {
+		b, err := decodeHex(tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		txs[i] = b
+	}
+
+	e := &headerEncoder{}
+	e.raw(p.ParentHash[:])
+	e.raw(emptyUncleHash[:])
+	e.raw(p.FeeRecipient[:])
+	e.raw(p.StateRoot[:])
+	e.raw(deriveListRoot(txs))
+	e.raw(p.ReceiptsRoot[:])
+	e.fixed("logsBloom", p.LogsBloom, 256)
+	e.raw(nil) // difficulty
+	e.quantity("blockNumber", p.BlockNumber)
+	e.quantity("gasLimit", p.GasLimit)
+	e.quantity("gasUsed", p.GasUsed)
+	e.quantity("timestamp", p.Timestamp)
+	e.bytes("extraData", p.ExtraData)
+	e.raw(p.PrevRandao[:])
+	e.raw(make([]byte, 8)) // nonce
+	e.bigQuantity("baseFeePerGas", p.BaseFeePerGas)
+
+	if p.Withdrawals != nil {
+		withdrawals := make([][]byte, len(p.Withdrawals))
+		for i, w := range p.Withdrawals {
+			b, err := encodeWithdrawal(w)
+			if err != nil {
+				return nil, fmt.Errorf("withdrawal %d: %v", i, err)
+			}
+			withdrawals[i] = b
+		}
+		e.raw(deriveListRoot(withdrawals))
+	}
+	if p.BlobGasUsed != nil || p.ExcessBlobGas != nil {
+		if p.BlobGasUsed == nil || p.ExcessBlobGas == nil {
+			return nil, fmt.Errorf("blobGasUsed and excessBlobGas must be set together")
+		}
+		if parentBeaconBlockRoot == nil {
+			return nil, fmt.Errorf("parentBeaconBlockRoot is required for payloads with blob gas fields")
+		}
+		e.quantity("blobGasUsed", *p.BlobGasUsed)
+		e.quantity("excessBlobGas", *p.ExcessBlobGas)
+		e.raw(parentBeaconBlockRoot[:])
+	}
+	if requestsHash != nil {
+		e.raw(requestsHash[:])
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return rlpList(e.fields...), nil
+}
+
+// headerEncoder accumulates RLP-encoded header fields, stopping at the first
+// malformed value
+type headerEncoder struct {
+	fields [][]byte
+	err    error
+}
+
+func (e *headerEncoder) raw(b []byte) {
+	e.fields = append(e.fields, rlpBytes(b))
+}
+
+func (e *headerEncoder) fail(name string, err error) {
+	if e.err == nil {
+		e.err = fmt.Errorf("%s: %v", name, err)
+	}
+}
+
+func (e *headerEncoder) fixed(name, value string, n int) {
+	b, err := decodeFixedHex(value, n)
+	if err != nil {
+		e.fail(name, err)
+		return
+	}
+	e.raw(b)
+}
+
+func (e *headerEncoder) bytes(name, value string) {
+	b, err := decodeHex(value)
+	if err != nil {
+		e.fail(name, err)
+		return
+	}
+	e.raw(b)
+}
+
+func (e *headerEncoder) quantity(name, value string) {
+	v, err := decodeQuantity(value)
+	if err != nil {
+		e.fail(name, err)
+		return
+	}
+	e.fields = append(e.fields, rlpUint(v))
+}
+
+func (e *headerEncoder) bigQuantity(name, value string) {
+	v, err := decodeBigQuantity(value)
+	if err != nil {
+		e.fail(name, err)
+		return
+	}
+	e.fields = append(e.fields, rlpBigInt(v))
+}
+
+func encodeWithdrawal(w Withdrawal) ([]byte, error) {
+	index, err := decodeQuantity(w.Index)
+	if err != nil {
+		return nil, fmt.Errorf("index: %v", err)
+	}
+	validator, err := decodeQuantity(w.ValidatorIndex)
+	if err != nil {
+		return nil, fmt.Errorf("validatorIndex: %v", err)
+	}
+	amount, err := decodeQuantity(w.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("amount: %v", err)
+	}
+	return rlpList(rlpUint(index), rlpUint(validator), rlpBytes(w.Address[:]), rlpUint(amount)), nil
+}
@@ -0,0 +1,30 @@
+package engineclient
+
+import "testing"
+
+func TestComputeBlockHashMainnet(t *testing.T) {
+	for _, file := range []string{"mainnet_shanghai.json", "mainnet_cancun.json"} {
+		t.Run(file, func(t *testing.T) {
+			call := loadMainnetPayload(t, file)
+			p := &call.ExecutionPayload
+			got, err := ComputeBlockHash(p, call.ParentBeaconBlockRoot, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != p.BlockHash {
+				t.Fatalf("computed %s, want mainnet block hash %s", got, p.BlockHash)
+			}
+
+			tampered := *p
+			tampered.Transactions = tampered.Transactions[1:]
+			if err := VerifyBlockHash(&tampered, call.ParentBeaconBlockRoot, nil); err == nil {
+				t.Fatal("verified a payload with a transaction dropped")
+			}
+			if call.ParentBeaconBlockRoot != nil {
+				if err := VerifyBlockHash(p, &Hash{}, nil); err == nil {
+					t.Fatal("verified a Cancun payload against the wrong beacon block root")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package engineclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+// RangeBody is one block delivered by StreamPayloadBodiesByRange. Body is nil
+// when the EL no longer has the block, matching the null entries of a
+// getPayloadBodiesByRange response.
+type RangeBody struct {
+	Number uint64
+	Body   *PayloadBody
+}
+
+// rangePages splits count blocks from start into consecutive pages of at most
+// size blocks
+func rangePages(start, count, size uint64) iter.Seq2[uint64, uint64] {
+	return func(yield func(uint64, uint64) bool) {
+		for count > 0 {
+			n := min(count, size)
+			if !yield(start, n) {
+				return
+			}
+			start += n
+			count -= n
+		}
+	}
+}
+
+// StreamPayloadBodiesByRange yields the bodies of count consecutive blocks
+// starting at start, requesting pageSize blocks per call (at most
+// maxBodiesPerRequest). Each response array is decoded one body at a time so
+// ranges of many thousands of blocks never sit in memory at once. Iteration
+// ends early when the EL truncates a page at its head, and after the first
+// error.
+//
+// Streamed calls are not retried or cached, and are bounded only by ctx
+// rather than the client's overall request timeout.
+func (c *EngineClient) StreamPayloadBodiesByRange(ctx context.Context, start, count, pageSize uint64) iter.Seq2[RangeBody, error] {
+	if pageSize == 0 || pageSize > maxBodiesPerRequest {
+		pageSize = maxBodiesPerRequest
+	}
+	return func(yield func(RangeBody, error) bool) {
+		for from, n := range rangePages(start, count, pageSize) {
+			number := from
+			stopped := false
+			err := c.streamBodiesPage(ctx, from, n, func(body *PayloadBody) bool {
+				if !yield(RangeBody{Number: number, Body: body}, nil) {
+					stopped = true
+					return false
+				}
+				number++
+				return true
+			})
+			if err != nil {
+				yield(RangeBody{}, fmt.Errorf("bodies %d-%d: %w", from, from+n-1, err))
+				return
+			}
+			if stopped || number < from+n {
+				return
+			}
+		}
+	}
+}
+
+// streamBodiesPage sends one getPayloadBodiesByRangeV1 call and passes each
+// entry of the result array to yield as it is decoded
+func (c *EngineClient) streamBodiesPage(ctx context.Context, from, count uint64, yield func(*PayloadBody) bool) error {
+	call := requestInfo{method: "engine_getPayloadBodiesByRangeV1", id: c.nextID.Add(1), endpoint: c.endpoint}
+	if err := c.guardCall(ctx, call.method); err != nil {
+		return err
+	}
+	if c.requestUUIDs {
+		call.uuid = newUUID()
+	}
+	if c.balancer != nil {
+		call.endpoint = c.balancer.pick(c.clock.Now())
+	}
+	params := []interface{}{fmt.Sprintf("0x%x", from), fmt.Sprintf("0x%x", count)}
+
+	start := c.clock.Now()
+	err := c.decodeBodiesStream(ctx, call, params, yield)
+	if c.balancer != nil {
+		// A JSON-RPC error is the endpoint answering, so as in makeRequest
+		// it does not count against the endpoint's health
+		failure := err
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			failure = nil
+		}
+		c.balancer.report(call.endpoint, failure, c.clock.Now())
+	}
+	attrs := append(call.logAttrs(), "duration", c.clock.Now().Sub(start))
+	if err != nil {
+		c.logger.Warn("engine call failed", append(attrs, "err", err)...)
+		return &RequestError{Method: call.method, ID: call.id, UUID: call.uuid, Err: err}
+	}
+	if c.keepAlive != nil && call.endpoint == c.endpoint {
+		c.markActive()
+	}
+	c.logger.Debug("engine call", attrs...)
+	return nil
+}
+
+// decodeBodiesStream decodes the result array of a response one body at a
+// time. Bodies are only yielded once the response id has been checked: as
+// they arrive when the id comes first, as ELs send it, and otherwise after
+// the whole response has been read.
+func (c *EngineClient) decodeBodiesStream(ctx context.Context, call requestInfo, params interface{}, yield func(*PayloadBody) bool) error {
+	req, requestBody, err := c.newHTTPRequest(ctx, call, params)
+	if err != nil {
+		return err
+	}
+	tracer := newCallTracer()
+	req = req.WithContext(tracer.withTrace(req.Context()))
+	defer func() { c.reportTiming(tracer.timing(call)) }()
+	client := &http.Client{Transport: c.client.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("failed to make request: %w", wrapTimeout(err))
+		c.capture(call, req, requestBody, nil, nil, tracer.timing(call), err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	body := io.Reader(resp.Body)
+	if c.captureHook != nil {
+		raw := &bytes.Buffer{}
+		body = io.TeeReader(resp.Body, raw)
+		defer func() {
+			io.Copy(io.Discard, io.LimitReader(body, maxCaptureTrailingBody))
+			c.capture(call, req, requestBody, resp, raw.Bytes(), tracer.timing(call), nil)
+		}()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), c.clock.Now()),
+		}
+	}
+
+	dec := json.NewDecoder(body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	// Only the id and error members are kept, so the id can be checked the
+	// same way as a buffered response. A null id is only checked once the
+	// object is complete, since whether it is allowed depends on the error.
+	envelope := map[string]interface{}{}
+	idChecked := false
+	var pending []*PayloadBody
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("failed to decode response: %w", wrapTimeout(err))
+		}
+		key, _ := tok.(string)
+		switch key {
+		case "result":
+			tok, err := dec.Token()
+			if err != nil {
+				return fmt.Errorf("failed to decode response: %w", wrapTimeout(err))
+			}
+			if tok == nil {
+				continue
+			}
+			if d, ok := tok.(json.Delim); !ok || d != '[' {
+				return fmt.Errorf("failed to decode response: expected result array, got %v", tok)
+			}
+			for dec.More() {
+				var entry *PayloadBody
+				if err := dec.Decode(&entry); err != nil {
+					return fmt.Errorf("failed to decode payload body: %w", wrapTimeout(err))
+				}
+				if !idChecked {
+					pending = append(pending, entry)
+				} else if !yield(entry) {
+					return nil
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+		case "error":
+			var rpcErr *RPCError
+			if err := dec.Decode(&rpcErr); err != nil {
+				return fmt.Errorf("failed to decode response: %w", wrapTimeout(err))
+			}
+			if rpcErr != nil {
+				envelope["error"] = rpcErr
+			}
+		case "id":
+			var id interface{}
+			if err := dec.Decode(&id); err != nil {
+				return fmt.Errorf("failed to decode response: %w", wrapTimeout(err))
+			}
+			envelope["id"] = id
+			if id != nil {
+				if err := checkResponseID(c.idPolicy, call.id, envelope); err != nil {
+					return err
+				}
+				idChecked = true
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("failed to decode response: %w", wrapTimeout(err))
+			}
+		}
+	}
+	if err := checkResponseID(c.idPolicy, call.id, envelope); err != nil {
+		return err
+	}
+	if rpcErr, ok := envelope["error"].(*RPCError); ok {
+		return rpcErr
+	}
+	for _, entry := range pending {
+		if !yield(entry) {
+			return nil
+		}
+	}
+	// Skip anything after the object so the connection can be reused.
+	io.Copy(io.Discard, body)
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %v", wrapTimeout(err))
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("failed to decode response: expected %q, got %v", want, tok)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamPayloadBodiesChecksIDBeforeYielding(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(id uint64) string
+		bodies  int
+		wantErr bool
+	}{
+		{"id first", func(id uint64) string {
+			return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":[{"transactions":[]},null]}`, id)
+		}, 2, false},
+		{"id last", func(id uint64) string {
+			return fmt.Sprintf(`{"jsonrpc":"2.0","result":[{"transactions":[]},null],"id":%d}`, id)
+		}, 2, false},
+		{"wrong id first", func(id uint64) string {
+			return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":[{"transactions":[]},null]}`, id+1)
+		}, 0, true},
+		{"wrong id last", func(id uint64) string {
+			return fmt.Sprintf(`{"jsonrpc":"2.0","result":[{"transactions":[]},null],"id":%d}`, id+1)
+		}, 0, true},
+		{"no id", func(uint64) string {
+			return `{"jsonrpc":"2.0","result":[{"transactions":[]},null]}`
+		}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					ID uint64 `json:"id"`
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				fmt.Fprint(w, tt.respond(req.ID))
+			}))
+			defer srv.Close()
+			c := NewEngineClient(srv.URL, nil, WithoutAuth())
+
+			bodies := 0
+			var err error
+			for _, e := range c.StreamPayloadBodiesByRange(context.Background(), 1, 2, 2) {
+				if e != nil {
+					err = e
+					break
+				}
+				bodies++
+			}
+			if bodies != tt.bodies || (err != nil) != tt.wantErr {
+				t.Fatalf("got %d bodies and error %v, want %d bodies, error %v", bodies, err, tt.bodies, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStreamPayloadBodiesRPCErrorKeepsReadEndpoint(t *testing.T) {
+	srv := stubEL(t, func(string, []json.RawMessage) (interface{}, *RPCError) {
+		return nil, &RPCError{Code: -38004, Message: "too large request"}
+	})
+	c := NewEngineClient(srv.URL, nil, WithoutAuth(), WithReadEndpoints(srv.URL))
+
+	for range 5 {
+		for _, err := range c.StreamPayloadBodiesByRange(context.Background(), 1, 2, 2) {
+			if err == nil {
+				t.Fatal("got a body, want the EL's error")
+			}
+		}
+	}
+	for _, e := range c.Status().ReadEndpoints {
+		if !e.Healthy || e.Failures != 0 {
+			t.Fatalf("endpoint %s marked down by JSON-RPC errors: %+v", e.URL, e)
+		}
+	}
+}
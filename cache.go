@@ -0,0 +1,115 @@
+package engineclient
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTLs lists the methods WithResponseCache caches when no TTLs
+// are given. Payload bodies and blocks looked up by hash never change once
+// known; the client version only changes when the EL restarts.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"engine_getPayloadBodiesByHashV1": 10 * time.Minute,
+	"eth_getBlockByHash":              10 * time.Minute,
+	"engine_getClientVersionV1":       time.Minute,
+}
+
+// responseCache is an LRU of successful responses keyed by method and
+// encoded params. Entries are stored encoded so callers never share maps.
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	ttls    map[string]time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key      string
+	response []byte
+	expires  time.Time
+}
+
+func newResponseCache(size int, ttls map[string]time.Duration) *responseCache {
+	return &responseCache{
+		size:    size,
+		ttls:    ttls,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// key returns the cache key for a call, or false if the method is not cached
+func (rc *responseCache) key(method string, params interface{}) (string, bool) {
+	if rc.ttls[method] <= 0 {
+		return "", false
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	return method + string(encoded), true
+}
+
+func (rc *responseCache) get(key string, now time.Time) (map[string]interface{}, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	el, ok := rc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if now.After(entry.expires) {
+		rc.order.Remove(el)
+		delete(rc.entries, key)
+		return nil, false
+	}
+	rc.order.MoveToFront(el)
+	var response map[string]interface{}
+	if err := json.Unmarshal(entry.response, &response); err != nil {
+		return nil, false
+	}
+	return response, true
+}
+
+// put stores a response unless it is an error or holds an unknown (null)
+// block or body, which may become known later
+func (rc *responseCache) put(key, method string, response map[string]interface{}, now time.Time) {
+	if response["error"] != nil || !cacheableResult(response["result"]) {
+		return
+	}
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry := &cacheEntry{key: key, response: encoded, expires: now.Add(rc.ttls[method])}
+	if el, ok := rc.entries[key]; ok {
+		el.Value = entry
+		rc.order.MoveToFront(el)
+		return
+	}
+	rc.entries[key] = rc.order.PushFront(entry)
+	for rc.order.Len() > rc.size {
+		oldest := rc.order.Back()
+		rc.order.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func cacheableResult(result interface{}) bool {
+	switch v := result.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if item == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,254 @@
+package engineclient
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fork names an execution layer upgrade that changed the engine API
+type Fork string
+
+const (
+	ForkParis    Fork = "paris"
+	ForkShanghai Fork = "shanghai"
+	ForkCancun   Fork = "cancun"
+	ForkPrague   Fork = "prague"
+)
+
+// forkOrder lists forks oldest first
+var forkOrder = []Fork{ForkParis, ForkShanghai, ForkCancun, ForkPrague}
+
+// forkAtLeast reports whether fork is base or a later fork. Like the method
+// registry, it treats a fork it does not know as newer than every known one.
+func forkAtLeast(fork, base Fork) bool {
+	i := slices.Index(forkOrder, fork)
+	return i < 0 || i >= slices.Index(forkOrder, base)
+}
+
+// ParseFork resolves a fork name, case-insensitively
+func ParseFork(name string) (Fork, error) {
+	fork := Fork(strings.ToLower(strings.TrimSpace(name)))
+	if !slices.Contains(forkOrder, fork) {
+		return "", fmt.Errorf("unknown fork %q", name)
+	}
+	return fork, nil
+}
+
+// CapabilityReport is the result of comparing the methods needed for a fork
+// schedule against those the EL advertises
+type CapabilityReport struct {
+	Supported []string
+	// Required lists, per fork, the methods the client's registry resolves
+	// for it
+	Required map[Fork][]string
+	Missing  map[Fork][]string
+}
+
+// String renders a per-fork matrix of required methods
+func (r *CapabilityReport) String() string {
+	supported := make(map[string]bool, len(r.Supported))
+	for _, m := range r.Supported {
+		supported[m] = true
+	}
+	var sb strings.Builder
+	for _, fork := range forkOrder {
+		if _, checked := r.Missing[fork]; !checked {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:\n", fork)
+		for _, m := range r.Required[fork] {
+			mark := "ok"
+			if !supported[m] {
+				mark = "MISSING"
+			}
+			fmt.Fprintf(&sb, "  %-7s %s\n", mark, m)
+		}
+	}
+	return sb.String()
+}
+
+// MissingCapabilitiesError lists, per fork, the methods the EL did not
+// advertise
+type MissingCapabilitiesError struct {
+	Missing map[Fork][]string
+}
+
+func (e *MissingCapabilitiesError) Error() string {
+	var parts []string
+	for _, fork := range forkOrder {
+		if methods := e.Missing[fork]; len(methods) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", fork, strings.Join(methods, ", ")))
+		}
+	}
+	return "EL is missing engine methods for " + strings.Join(parts, "; ")
+}
+
+// CheckCapabilities calls engine_exchangeCapabilities with every method the
+// client's registry resolves for the given forks and reports which are
+// missing. Missing methods are logged
+// as warnings and returned as a *MissingCapabilitiesError alongside the
+// report, so fork-readiness problems show up before the fork activates.
+func (c *EngineClient) CheckCapabilities(ctx context.Context, forks ...Fork) (*CapabilityReport, error) {
+	required := make(map[Fork][]string, len(forks))
+	var wanted []string
+	for _, fork := range forks {
+		methods, err := c.methods.Methods(fork)
+		if err != nil {
+			return nil, err
+		}
+		required[fork] = methods
+		for _, m := range methods {
+			if !slices.Contains(wanted, m) {
+				wanted = append(wanted, m)
+			}
+		}
+	}
+	response, err := c.makeRequest(ctx, "engine_exchangeCapabilities", []interface{}{wanted})
+	if err != nil {
+		return nil, err
+	}
+	var supported []string
+	if err := decodeResult(response, &supported); err != nil {
+		return nil, err
+	}
+	sort.Strings(supported)
+
+	report := &CapabilityReport{Supported: supported, Required: required, Missing: make(map[Fork][]string)}
+	has := make(map[string]bool, len(supported))
+	for _, m := range supported {
+		has[m] = true
+	}
+	missing := false
+	for _, fork := range forks {
+		report.Missing[fork] = []string{}
+		for _, m := range required[fork] {
+			if !has[m] {
+				report.Missing[fork] = append(report.Missing[fork], m)
+				c.logger.Warn("EL does not support engine method", "fork", fork, "method", m)
+				missing = true
+			}
+		}
+	}
+	if missing {
+		return report, &MissingCapabilitiesError{Missing: report.Missing}
+	}
+	return report, nil
+}
+
+const (
+	// capabilityRetryMin and capabilityRetryMax bound the wait before a
+	// capability check that could not reach the EL is tried again
+	capabilityRetryMin = time.Second
+	capabilityRetryMax = time.Minute
+)
+
+// capabilityGuard runs CheckCapabilities once around the first engine call.
+// Only the call that starts a check waits for it; concurrent calls go ahead
+// unchecked. A check that could not reach the EL is retried by a later call
+// once a backoff has passed, and missing methods are reported but never
+// block calls.
+type capabilityGuard struct {
+	forks   []Fork
+	handler func(*CapabilityReport, error)
+
+	mu      sync.Mutex
+	checked bool
+	running bool
+	retryAt time.Time
+	backoff time.Duration
+}
+
+func (g *capabilityGuard) check(ctx context.Context, c *EngineClient) {
+	g.mu.Lock()
+	if g.checked || g.running || c.clock.Now().Before(g.retryAt) {
+		g.mu.Unlock()
+		return
+	}
+	g.running = true
+	g.mu.Unlock()
+
+	forks := g.forks
+	if len(forks) == 0 {
+		forks = []Fork{c.fork}
+	}
+	report, err := c.CheckCapabilities(ctx, forks...)
+
+	g.mu.Lock()
+	g.running = false
+	if report == nil {
+		g.backoff = min(max(2*g.backoff, capabilityRetryMin), capabilityRetryMax)
+		g.retryAt = c.clock.Now().Add(g.backoff)
+		retryIn := g.backoff
+		g.mu.Unlock()
+		c.logger.Warn("capability check failed", "err", err, "retryIn", retryIn)
+		return
+	}
+	g.checked = true
+	g.mu.Unlock()
+	if g.handler != nil {
+		g.handler(report, err)
+	}
+}
+
+// runCapabilities prints which engine methods the EL supports for each fork
+// and fails if any are missing
+func runCapabilities(args []string) error {
+	fs := flag.NewFlagSet("capabilities", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	forkList := fs.String("forks", "paris,shanghai,cancun,prague", "comma-separated forks to check")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout for the call")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	defer out.batch()()
+
+	var forks []Fork
+	for _, name := range strings.Split(*forkList, ",") {
+		fork, err := ParseFork(name)
+		if err != nil {
+			return err
+		}
+		forks = append(forks, fork)
+	}
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	report, err := client.CheckCapabilities(ctx, forks...)
+	if report == nil {
+		return err
+	}
+	if out.format == outputText {
+		out.emit(nil, func(w io.Writer) { fmt.Fprint(w, report) })
+		return err
+	}
+	supported := make(map[string]bool, len(report.Supported))
+	for _, m := range report.Supported {
+		supported[m] = true
+	}
+	for _, fork := range forks {
+		for _, m := range report.Required[fork] {
+			out.emit(record{{"fork", string(fork)}, {"method", m}, {"supported", supported[m]}}, nil)
+		}
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCapabilityCheck(t *testing.T) {
+	var checks [][]string
+	srv := stubEL(t, func(method string, params []json.RawMessage) (interface{}, *RPCError) {
+		if method != "engine_exchangeCapabilities" {
+			return PayloadStatus{Status: StatusValid}, nil
+		}
+		var wanted []string
+		json.Unmarshal(params[0], &wanted)
+		checks = append(checks, wanted)
+		return slices.DeleteFunc(slices.Clone(wanted), func(m string) bool { return m == "engine_getPayloadV4" }), nil
+	})
+	var report *CapabilityReport
+	var reportErr error
+	c := NewEngineClient(srv.URL, nil, WithoutAuth(), WithFork(ForkPrague), WithCapabilityCheck(func(r *CapabilityReport, err error) {
+		report, reportErr = r, err
+	}))
+	for range 2 {
+		if _, err := c.NewPayload(context.Background(), map[string]interface{}{"blockHash": Hash{1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(checks) != 1 {
+		t.Fatalf("exchangeCapabilities sent %d times, want once", len(checks))
+	}
+	want := []string{
+		"engine_newPayloadV4",
+		"engine_forkchoiceUpdatedV3",
+		"engine_getPayloadV4",
+		"engine_getPayloadBodiesByHashV1",
+		"engine_getPayloadBodiesByRangeV1",
+	}
+	if !slices.Equal(checks[0], want) {
+		t.Fatalf("checked %v, want %v", checks[0], want)
+	}
+	var missing *MissingCapabilitiesError
+	if !errors.As(reportErr, &missing) || !slices.Equal(missing.Missing[ForkPrague], []string{"engine_getPayloadV4"}) {
+		t.Fatalf("got %v, want engine_getPayloadV4 missing for prague", reportErr)
+	}
+	if report == nil || !slices.Equal(report.Required[ForkPrague], want) {
+		t.Fatalf("got report %+v", report)
+	}
+}
+
+func TestCapabilityCheckDoesNotBlockCalls(t *testing.T) {
+	var exchanges atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := stubEL(t, func(method string, params []json.RawMessage) (interface{}, *RPCError) {
+		if method != "engine_exchangeCapabilities" {
+			return PayloadStatus{Status: StatusValid}, nil
+		}
+		if exchanges.Add(1) == 1 {
+			close(started)
+			<-release
+			return nil, &RPCError{Code: -32603, Message: "not ready"}
+		}
+		var wanted []string
+		json.Unmarshal(params[0], &wanted)
+		return wanted, nil
+	})
+	clock := NewManualClock(time.Unix(1700000000, 0))
+	checked := make(chan error, 1)
+	c := NewEngineClient(srv.URL, nil, WithoutAuth(), WithClock(clock), WithCapabilityCheck(func(_ *CapabilityReport, err error) {
+		checked <- err
+	}))
+	ctx := context.Background()
+	payload := map[string]interface{}{"blockHash": Hash{1}}
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.NewPayload(ctx, payload)
+		first <- err
+	}()
+	<-started
+	concurrent := make(chan error, 1)
+	go func() {
+		_, err := c.NewPayload(ctx, payload)
+		concurrent <- err
+	}()
+	select {
+	case err := <-concurrent:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a concurrent call waited on the capability check")
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.NewPayload(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	if got := exchanges.Load(); got != 1 {
+		t.Fatalf("failed check retried before its backoff: %d exchanges", got)
+	}
+	clock.Advance(capabilityRetryMin)
+	if _, err := c.NewPayload(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	if got := exchanges.Load(); got != 2 {
+		t.Fatalf("failed check not retried after its backoff: %d exchanges", got)
+	}
+	if err := <-checked; err != nil {
+		t.Fatalf("retried check reported %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package engineclient
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxCaptureTrailingBody bounds how much of a reply is read solely so it can
+// be captured, such as the body of a non-200 status
+const maxCaptureTrailingBody = 1 << 20
+
+// Capture is the wire-level record of one HTTP exchange with the EL. The
+// credentials of the Authorization and Proxy-Authorization headers are
+// redacted, keeping their scheme; everything else is as sent and received.
+type Capture struct {
+	Method   string
+	ID       uint64
+	Endpoint string
+	Time     time.Time
+	Duration time.Duration
+	Timing   CallTiming
+
+	RequestHeader http.Header
+	Request       []byte
+
+	// StatusCode, ResponseHeader and Response are empty when the request
+	// failed before a reply arrived, in which case Err is set
+	StatusCode     int
+	ResponseHeader http.Header
+	Response       []byte
+	Err            error
+}
+
+func (c *EngineClient) capture(call requestInfo, req *http.Request, requestBody []byte, resp *http.Response, responseBody []byte, timing CallTiming, err error) {
+	if c.captureHook == nil {
+		return
+	}
+	header := req.Header.Clone()
+	for _, name := range credentialHeaders {
+		for i, value := range header.Values(name) {
+			header[name][i] = redactCredentials(value)
+		}
+	}
+	record := Capture{
+		Method:        call.method,
+		ID:            call.id,
+		Endpoint:      call.endpoint,
+		Time:          timing.Start,
+		Duration:      timing.Total,
+		Timing:        timing,
+		RequestHeader: header,
+		Request:       requestBody,
+		Err:           err,
+	}
+	if resp != nil {
+		record.StatusCode = resp.StatusCode
+		record.ResponseHeader = resp.Header.Clone()
+		record.Response = responseBody
+	}
+	c.captureHook(record)
+}
+
+// credentialHeaders are the request headers whose credentials captures
+// redact, including one a WithHeader option may add for a proxy
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization"}
+
+// redactCredentials replaces the credentials of an authorization value,
+// keeping its scheme so a replaced JWT still shows what was sent instead
+func redactCredentials(value string) string {
+	if scheme, _, ok := strings.Cut(strings.TrimSpace(value), " "); ok {
+		return scheme + " [redacted]"
+	}
+	return "[redacted]"
+}
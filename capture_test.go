@@ -0,0 +1,40 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestCaptureRedactsCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		opts   []Option
+		want   []string
+	}{
+		{"jwt", "Authorization", nil, []string{"Bearer [redacted]"}},
+		{"replaced", "Authorization", []Option{WithHeader("Authorization", "Basic dXNlcjpwYXNz")}, []string{"Basic [redacted]"}},
+		{"no scheme", "Authorization", []Option{WithHeader("Authorization", "s3cret")}, []string{"[redacted]"}},
+		{"proxy", "Proxy-Authorization", []Option{WithHeader("Proxy-Authorization", "Basic dXNlcjpwYXNz")}, []string{"Basic [redacted]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := stubEL(t, func(string, []json.RawMessage) (interface{}, *RPCError) {
+				return "0x1", nil
+			})
+			var got []string
+			opts := append(tt.opts, WithCaptureHook(func(c Capture) {
+				got = c.RequestHeader.Values(tt.header)
+			}))
+			c := NewEngineClient(srv.URL, []byte("0123456789abcdef0123456789abcdef"), opts...)
+			if _, err := c.Call(context.Background(), "eth_chainId", []interface{}{}); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("captured %s %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
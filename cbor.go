@@ -0,0 +1,55 @@
+package engineclient
+
+import "encoding/binary"
+
+// CBOR major types from RFC 8949 section 3.1
+const (
+	cborUint   = 0
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborSimple = 7
+
+	cborNull = 22
+)
+
+// cborEncoder writes the small subset of CBOR needed to export payload
+// bodies: unsigned integers, byte and text strings, arrays, maps and null
+type cborEncoder struct {
+	buf []byte
+}
+
+func (e *cborEncoder) head(major byte, v uint64) {
+	m := major << 5
+	switch {
+	case v < 24:
+		e.buf = append(e.buf, m|byte(v))
+	case v <= 0xff:
+		e.buf = append(e.buf, m|24, byte(v))
+	case v <= 0xffff:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, m|25), uint16(v))
+	case v <= 0xffffffff:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, m|26), uint32(v))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, m|27), v)
+	}
+}
+
+func (e *cborEncoder) uint(v uint64) { e.head(cborUint, v) }
+
+func (e *cborEncoder) bytes(b []byte) {
+	e.head(cborBytes, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *cborEncoder) text(s string) {
+	e.head(cborText, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *cborEncoder) array(n int) { e.head(cborArray, uint64(n)) }
+
+func (e *cborEncoder) mapHeader(n int) { e.head(cborMap, uint64(n)) }
+
+func (e *cborEncoder) null() { e.head(cborSimple, cborNull) }
@@ -0,0 +1,209 @@
+package engineclient
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Network is the identity of a public chain
+type Network struct {
+	ChainID     uint64
+	GenesisHash Hash
+}
+
+// KnownNetworks lists the public networks VerifyChain can check by name
+var KnownNetworks = map[string]Network{
+	"mainnet": {1, MustHexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3")},
+	"sepolia": {11155111, MustHexToHash("0x25a5cc106eea7138acab33231d7160d69cb777ee0c2c553fcddf5138993e6dd9")},
+	"holesky": {17000, MustHexToHash("0xb5f7f912443c940f21fd611f12828d75b534364ed9e95ca4e307729a4661bde4")},
+}
+
+// ChainMismatchError reports an EL on a different network than expected
+type ChainMismatchError struct {
+	Field string
+	Want  string
+	Got   string
+}
+
+func (e *ChainMismatchError) Error() string {
+	return fmt.Sprintf("EL is on the wrong network: %s is %s, want %s", e.Field, e.Got, e.Want)
+}
+
+type primaryEndpointKey struct{}
+
+// withPrimaryEndpoint keeps calls made with ctx on the primary endpoint even
+// when they are read methods the balancer would otherwise spread
+func withPrimaryEndpoint(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryEndpointKey{}, true)
+}
+
+func pinnedToPrimary(ctx context.Context) bool {
+	pinned, _ := ctx.Value(primaryEndpointKey{}).(bool)
+	return pinned
+}
+
+// GetBlockByNumber sends eth_getBlockByNumber for a hex number or a tag such
+// as "latest"
+func (c *EngineClient) GetBlockByNumber(ctx context.Context, number string, fullTransactions bool) (map[string]interface{}, error) {
+	return c.makeRequest(ctx, "eth_getBlockByNumber", []interface{}{number, fullTransactions})
+}
+
+// ChainID returns the EL's eth_chainId
+func (c *EngineClient) ChainID(ctx context.Context) (uint64, error) {
+	response, err := c.makeRequest(ctx, "eth_chainId", []interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	var id string
+	if err := decodeResult(response, &id); err != nil {
+		return 0, err
+	}
+	return decodeQuantity(id)
+}
+
+// GenesisHash returns the hash of the EL's block 0
+func (c *EngineClient) GenesisHash(ctx context.Context) (Hash, error) {
+	response, err := c.GetBlockByNumber(ctx, "0x0", false)
+	if err != nil {
+		return Hash{}, err
+	}
+	var block *struct {
+		Hash Hash `json:"hash"`
+	}
+	if err := decodeResult(response, &block); err != nil {
+		return Hash{}, err
+	}
+	if block == nil {
+		return Hash{}, fmt.Errorf("EL has no genesis block")
+	}
+	return block.Hash, nil
+}
+
+// VerifyChain confirms the primary endpoint's chain ID and genesis hash match
+// the expected network, returning a *ChainMismatchError when they do not. A
+// zero expectedChainID or expectedGenesisHash skips that check.
+func (c *EngineClient) VerifyChain(ctx context.Context, expectedChainID uint64, expectedGenesisHash Hash) error {
+	ctx = withPrimaryEndpoint(ctx)
+	if expectedChainID != 0 {
+		id, err := c.ChainID(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get chain ID: %w", err)
+		}
+		if id != expectedChainID {
+			return &ChainMismatchError{Field: "chain ID", Want: fmt.Sprint(expectedChainID), Got: fmt.Sprint(id)}
+		}
+	}
+	if expectedGenesisHash != (Hash{}) {
+		genesis, err := c.GenesisHash(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get genesis block: %w", err)
+		}
+		if genesis != expectedGenesisHash {
+			return &ChainMismatchError{Field: "genesis hash", Want: expectedGenesisHash.Hex(), Got: genesis.Hex()}
+		}
+	}
+	return nil
+}
+
+// chainGuard runs VerifyChain once before the first forkchoice update. A
+// failed check is retried on the next update, so a transient error does not
+// wedge the client, but no update is sent until a check has passed.
+type chainGuard struct {
+	network  Network
+	mu       sync.Mutex
+	verified bool
+}
+
+func (g *chainGuard) check(ctx context.Context, c *EngineClient) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.verified {
+		return nil
+	}
+	if err := c.VerifyChain(ctx, g.network.ChainID, g.network.GenesisHash); err != nil {
+		return fmt.Errorf("refusing forkchoice update: %w", err)
+	}
+	g.verified = true
+	return nil
+}
+
+// ParseNetwork resolves a known network name
+func ParseNetwork(name string) (Network, error) {
+	network, ok := KnownNetworks[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Network{}, fmt.Errorf("unknown network %q", name)
+	}
+	return network, nil
+}
+
+// runCheck verifies the EL is on the expected network
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	networkName := fs.String("network", "", "expected network: mainnet, sepolia or holesky (defaults to the configured network)")
+	chainID := fs.Uint64("chain-id", 0, "expected chain ID, for networks not known by name")
+	genesis := fs.String("genesis", "", "expected genesis block hash")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout for the check")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	if *networkName == "" {
+		*networkName = cfg.Network
+	}
+	var want Network
+	if *networkName != "" {
+		if want, err = ParseNetwork(*networkName); err != nil {
+			return err
+		}
+	}
+	if *chainID != 0 {
+		want.ChainID = *chainID
+	}
+	if *genesis != "" {
+		hash, err := HexToHash(*genesis)
+		if err != nil {
+			return fmt.Errorf("invalid -genesis: %v", err)
+		}
+		want.GenesisHash = hash
+	}
+	if want.ChainID == 0 && want.GenesisHash == (Hash{}) {
+		return fmt.Errorf("pass -network, -chain-id or -genesis")
+	}
+
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := client.VerifyChain(ctx, want.ChainID, want.GenesisHash); err != nil {
+		return err
+	}
+	rec := record{{"endpoint", cfg.Endpoint}, {"chainId", nil}, {"genesisHash", nil}, {"match", true}}
+	if want.ChainID != 0 {
+		rec[1].value = want.ChainID
+	}
+	if want.GenesisHash != (Hash{}) {
+		rec[2].value = want.GenesisHash
+	}
+	out.emit(rec, func(w io.Writer) {
+		fmt.Fprintf(w, "%s is on the expected network\n", cfg.Endpoint)
+	})
+	return nil
+}
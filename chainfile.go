@@ -0,0 +1,389 @@
+package engineclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// blobTxType is the EIP-4844 transaction type, whose payload carries the
+// versioned hashes newPayloadV3 expects alongside the block
+const blobTxType = 0x03
+
+// payloadFromRLPBlock converts an RLP-encoded block, as written by geth
+// export, into an import item. Prague blocks cannot be converted since the
+// block only commits to its execution requests by hash.
+func payloadFromRLPBlock(block []byte) (ImportPayload, error) {
+	parts, err := rlpListContent(block)
+	if err != nil {
+		return ImportPayload{}, fmt.Errorf("invalid block: %v", err)
+	}
+	if len(parts) < 3 {
+		return ImportPayload{}, fmt.Errorf("invalid block: %d fields", len(parts))
+	}
+	fields, err := rlpListContent(parts[0])
+	if err != nil {
+		return ImportPayload{}, fmt.Errorf("invalid header: %v", err)
+	}
+	if len(fields) < 16 {
+		return ImportPayload{}, fmt.Errorf("header has %d fields; pre-London blocks cannot be payloads", len(fields))
+	}
+	h := make([][]byte, len(fields))
+	for i, f := range fields {
+		if h[i], err = rlpString(f); err != nil {
+			return ImportPayload{}, fmt.Errorf("header field %d: %v", i, err)
+		}
+	}
+	if new(big.Int).SetBytes(h[7]).Sign() != 0 {
+		return ImportPayload{}, fmt.Errorf("block %s is pre-merge", rlpQuantity(h[8]))
+	}
+	if len(h) > 20 {
+		return ImportPayload{}, fmt.Errorf("block %s has execution requests, which RLP exports do not include", rlpQuantity(h[8]))
+	}
+
+	p := ExecutionPayload{
+		LogsBloom:     encodeHex(h[6]),
+		BlockNumber:   rlpQuantity(h[8]),
+		GasLimit:      rlpQuantity(h[9]),
+		GasUsed:       rlpQuantity(h[10]),
+		Timestamp:     rlpQuantity(h[11]),
+		ExtraData:     encodeHex(h[12]),
+		BaseFeePerGas: rlpQuantity(h[15]),
+		BlockHash:     Hash(keccak256(parts[0])),
+		Transactions:  []string{},
+	}
+	for _, f := range []struct {
+		dst []byte
+		src []byte
+	}{
+		{p.ParentHash[:], h[0]},
+		{p.FeeRecipient[:], h[2]},
+		{p.StateRoot[:], h[3]},
+		{p.ReceiptsRoot[:], h[5]},
+		{p.PrevRandao[:], h[13]},
+	} {
+		if len(f.src) != len(f.dst) {
+			return ImportPayload{}, fmt.Errorf("header field has %d bytes, want %d", len(f.src), len(f.dst))
+		}
+		copy(f.dst, f.src)
+	}
+
+	txs, err := rlpListContent(parts[1])
+	if err != nil {
+		return ImportPayload{}, fmt.Errorf("invalid transactions: %v", err)
+	}
+	var versionedHashes []Hash
+	for i, tx := range txs {
+		list, content, _, _ := rlpSplit(tx)
+		raw := tx // legacy transactions are embedded as lists
+		if !list {
+			raw = content
+		}
+		p.Transactions = append(p.Transactions, encodeHex(raw))
+		if !list && len(raw) > 0 && raw[0] == blobTxType {
+			hashes, err := blobVersionedHashes(raw[1:])
+			if err != nil {
+				return ImportPayload{}, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			versionedHashes = append(versionedHashes, hashes...)
+		}
+	}
+
+	item := ImportPayload{}
+	if len(h) > 16 {
+		p.Withdrawals = []Withdrawal{}
+		if len(parts) < 4 {
+			return ImportPayload{}, fmt.Errorf("block has a withdrawals root but no withdrawals")
+		}
+		withdrawals, err := rlpListContent(parts[3])
+		if err != nil {
+			return ImportPayload{}, fmt.Errorf("invalid withdrawals: %v", err)
+		}
+		for i, w := range withdrawals {
+			wf, err := rlpListContent(w)
+			if err != nil || len(wf) != 4 {
+				return ImportPayload{}, fmt.Errorf("invalid withdrawal %d", i)
+			}
+			var v [4][]byte
+			for j := range wf {
+				if v[j], err = rlpString(wf[j]); err != nil {
+					return ImportPayload{}, fmt.Errorf("withdrawal %d: %v", i, err)
+				}
+			}
+			if len(v[2]) != 20 {
+				return ImportPayload{}, fmt.Errorf("withdrawal %d: address has %d bytes", i, len(v[2]))
+			}
+			out := Withdrawal{Index: rlpQuantity(v[0]), ValidatorIndex: rlpQuantity(v[1]), Amount: rlpQuantity(v[3])}
+			copy(out.Address[:], v[2])
+			p.Withdrawals = append(p.Withdrawals, out)
+		}
+	}
+	if len(h) > 18 {
+		blobGasUsed, excessBlobGas := rlpQuantity(h[17]), rlpQuantity(h[18])
+		p.BlobGasUsed, p.ExcessBlobGas = &blobGasUsed, &excessBlobGas
+	}
+	if len(h) > 19 {
+		if len(h[19]) != 32 {
+			return ImportPayload{}, fmt.Errorf("parent beacon block root has %d bytes", len(h[19]))
+		}
+		root := Hash(h[19])
+		item.ParentBeaconBlockRoot = &root
+		item.VersionedHashes = append([]Hash{}, versionedHashes...)
+	}
+
+	item.Payload, err = payloadMap(&p)
+	return item, err
+}
+
+// blobVersionedHashes extracts the blob_versioned_hashes field of a blob
+// transaction payload
+func blobVersionedHashes(payload []byte) ([]Hash, error) {
+	fields, err := rlpListContent(payload)
+	if err != nil || len(fields) < 11 {
+		return nil, fmt.Errorf("invalid blob transaction")
+	}
+	items, err := rlpListContent(fields[10])
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob versioned hashes: %v", err)
+	}
+	hashes := make([]Hash, len(items))
+	for i, item := range items {
+		b, err := rlpString(item)
+		if err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("invalid blob versioned hash %d", i)
+		}
+		hashes[i] = Hash(b)
+	}
+	return hashes, nil
+}
+
+func rlpQuantity(b []byte) string {
+	return "0x" + new(big.Int).SetBytes(b).Text(16)
+}
+
+// payloadMap converts a typed payload into the loosely typed form sent on
+// the wire, dropping withdrawals before Shanghai
+func payloadMap(p *ExecutionPayload) (map[string]interface{}, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %v", err)
+	}
+	if p.Withdrawals == nil {
+		delete(m, "withdrawals")
+	}
+	return m, nil
+}
+
+// parseChainLine decodes one line of a JSONL chain file. A line may hold a
+// bare payload, an object with an executionPayload member and the extra
+// newPayload parameters, a JSON-RPC response or newPayload request wrapping
+// either, or a hex-encoded RLP block.
+func parseChainLine(line string) (ImportPayload, error) {
+	if strings.HasPrefix(line, "0x") || strings.HasPrefix(line, `"0x`) {
+		block, err := decodeHex(strings.Trim(line, `"`))
+		if err != nil {
+			return ImportPayload{}, err
+		}
+		return payloadFromRLPBlock(block)
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return ImportPayload{}, err
+	}
+	if params, ok := obj["params"].([]interface{}); ok && len(params) > 0 {
+		// A newPayload request: [payload, versionedHashes, root, requests]
+		obj = map[string]interface{}{"executionPayload": params[0]}
+		for i, key := range []string{"", "versionedHashes", "parentBeaconBlockRoot", "executionRequests"} {
+			if i > 0 && i < len(params) {
+				obj[key] = params[i]
+			}
+		}
+	} else if result, ok := obj["result"].(map[string]interface{}); ok {
+		obj = result
+	}
+
+	payload := obj
+	if inner, ok := obj["executionPayload"].(map[string]interface{}); ok {
+		payload = inner
+	}
+	if _, ok := payload["blockHash"]; !ok {
+		return ImportPayload{}, fmt.Errorf("line holds no execution payload")
+	}
+	item := ImportPayload{Payload: payload}
+	var extra struct {
+		VersionedHashes       []Hash   `json:"versionedHashes"`
+		ParentBeaconBlockRoot *Hash    `json:"parentBeaconBlockRoot"`
+		ExecutionRequests     []string `json:"executionRequests"`
+	}
+	raw, _ := json.Marshal(obj)
+	if err := json.Unmarshal(raw, &extra); err != nil {
+		return ImportPayload{}, err
+	}
+	item.ParentBeaconBlockRoot = extra.ParentBeaconBlockRoot
+	item.ExecutionRequests = extra.ExecutionRequests
+	if item.ParentBeaconBlockRoot != nil {
+		item.VersionedHashes = append([]Hash{}, extra.VersionedHashes...)
+	}
+	return item, nil
+}
+
+// readChainFile streams import items from r until it is exhausted, the
+// context is done or an entry fails to parse
+func readChainFile(ctx context.Context, r io.Reader, format string, out chan<- ImportPayload) error {
+	defer close(out)
+	send := func(item ImportPayload) error {
+		select {
+		case out <- item:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	switch format {
+	case "jsonl":
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			item, err := parseChainLine(line)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", n, err)
+			}
+			if err := send(item); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	case "rlp":
+		br := bufio.NewReader(r)
+		for n := 0; ; n++ {
+			block, err := readRLPItem(br)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("block %d: %v", n, err)
+			}
+			item, err := payloadFromRLPBlock(block)
+			if err != nil {
+				return fmt.Errorf("block %d: %v", n, err)
+			}
+			if err := send(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown format %q (want jsonl or rlp)", format)
+	}
+}
+
+// runImport replays an exported chain through newPayload and a final
+// forkchoiceUpdated, printing each block's status and timing
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	file := fs.String("file", "", "chain file to import")
+	format := fs.String("format", "jsonl", "file format: jsonl (payloads, one per line) or rlp (concatenated blocks as written by geth export)")
+	workers := fs.Int("workers", 4, "payloads prepared and submitted in parallel")
+	verify := fs.Bool("verify", false, "check block hashes locally before submitting")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	var opts []Option
+	if *verify {
+		opts = append(opts, WithBlockHashVerification())
+	}
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient(opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := make(chan ImportPayload, *workers)
+	readErr := make(chan error, 1)
+	go func() { readErr <- readChainFile(ctx, f, *format, items) }()
+
+	// The forkchoiceUpdated is only sent once the whole file has been read,
+	// so a truncated or corrupt file never moves the head to a partial chain.
+	report, err := client.ImportPayloads(ctx, items, ImportConfig{
+		Workers:        *workers,
+		SkipForkchoice: true,
+		OnResult: func(r ImportResult) {
+			rec := record{
+				{"block", r.Number}, {"hash", r.BlockHash}, {"status", nil},
+				{"duration", r.Duration.Round(time.Millisecond)}, {"error", r.Err},
+			}
+			if r.Status != nil {
+				rec[2].value = r.Status.Status
+			}
+			out.emit(rec, func(w io.Writer) {
+				if r.Err != nil {
+					fmt.Fprintf(w, "block %d %s: %v\n", r.Number, r.BlockHash, r.Err)
+					return
+				}
+				fmt.Fprintf(w, "block %d %s: %s (%s)\n", r.Number, r.BlockHash, r.Status.Status, r.Duration.Round(time.Millisecond))
+			})
+		},
+	})
+	if rerr := <-readErr; rerr != nil && rerr != context.Canceled {
+		return fmt.Errorf("%v; forkchoice left unchanged", rerr)
+	}
+	if err == nil {
+		err = client.applyImportedHead(ctx, report)
+	}
+	if report != nil {
+		rec := record{
+			{"submitted", report.Submitted}, {"failed", report.Failed},
+			{"head", report.Head}, {"elapsed", report.Elapsed.Round(time.Millisecond)},
+		}
+		out.emit(rec, func(w io.Writer) {
+			fmt.Fprintf(w, "imported %d payloads in %s, %d failed, head %s\n",
+				report.Submitted, report.Elapsed.Round(time.Millisecond), report.Failed, report.Head)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d payloads failed to import", report.Failed)
+	}
+	if n := report.Statuses[StatusInvalid] + report.Statuses[StatusInvalidBlockHash]; n > 0 {
+		return statusError(StatusInvalid, "%d payloads were rejected as invalid", n)
+	}
+	return nil
+}
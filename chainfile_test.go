@@ -0,0 +1,74 @@
+package engineclient
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// isolateConfig keeps the caller's environment and config file out of a
+// command run by a test
+func isolateConfig(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(configFileEnv, "")
+	for _, s := range settings {
+		for _, env := range s.env {
+			t.Setenv(env, "")
+		}
+	}
+}
+
+func TestImportSkipsForkchoiceAfterReadError(t *testing.T) {
+	lines := []string{
+		`{"parentHash":"0x0000000000000000000000000000000000000000000000000000000000000000","blockHash":"0x0100000000000000000000000000000000000000000000000000000000000000","blockNumber":"0x1"}`,
+		`{"parentHash":"0x0100000000000000000000000000000000000000000000000000000000000000","blockHash":"0x0200000000000000000000000000000000000000000000000000000000000000","blockNumber":"0x2"}`,
+	}
+	tests := []struct {
+		name          string
+		contents      string
+		wantErr       bool
+		wantHeadMoved bool
+	}{
+		{"complete file", strings.Join(lines, "\n") + "\n", false, true},
+		{"truncated file", lines[0] + "\n" + lines[1][:len(lines[1])/2], true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateConfig(t)
+			var mu sync.Mutex
+			var methods []string
+			srv := stubEL(t, func(method string, _ []json.RawMessage) (interface{}, *RPCError) {
+				mu.Lock()
+				methods = append(methods, method)
+				mu.Unlock()
+				if strings.HasPrefix(method, "engine_forkchoiceUpdated") {
+					return ForkchoiceUpdatedResult{PayloadStatus: PayloadStatus{Status: StatusValid}}, nil
+				}
+				return PayloadStatus{Status: StatusValid}, nil
+			})
+			path := filepath.Join(t.TempDir(), "chain.jsonl")
+			if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := runImport([]string{"-endpoint", srv.URL, "-file", path, "-workers", "1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			moved := false
+			for _, m := range methods {
+				if strings.HasPrefix(m, "engine_forkchoiceUpdated") {
+					moved = true
+				}
+			}
+			if moved != tt.wantHeadMoved {
+				t.Fatalf("sent %v, want forkchoiceUpdated %v", methods, tt.wantHeadMoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package engineclient
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the client's source of time for JWT iat and exp claims, token
+// reuse, retry backoff and per-method timeouts. Tests can supply a
+// ManualClock to freeze time and step it deterministically.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f once d has elapsed, never on the calling goroutine.
+	// The returned stop function prevents the call if it has not happened yet.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// sleep waits for d on the client's clock, returning false if ctx ends first
+func (c *EngineClient) sleep(ctx context.Context, d time.Duration) bool {
+	fired := make(chan struct{})
+	stop := c.clock.AfterFunc(d, func() { close(fired) })
+	select {
+	case <-fired:
+		return true
+	case <-ctx.Done():
+		stop()
+		return false
+	}
+}
+
+// withTimeout is context.WithTimeout on the client's clock
+func (c *EngineClient) withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.clock.(realClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	inner, cancel := context.WithCancelCause(ctx)
+	deadline := &clockDeadlineCtx{Context: inner, deadline: c.clock.Now().Add(d)}
+	stop := c.clock.AfterFunc(d, func() {
+		deadline.mu.Lock()
+		deadline.expired = true
+		deadline.mu.Unlock()
+		cancel(context.DeadlineExceeded)
+	})
+	return deadline, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// clockDeadlineCtx reports context.DeadlineExceeded once its clock deadline
+// has passed, as a context from context.WithTimeout would
+type clockDeadlineCtx struct {
+	context.Context
+	deadline time.Time
+	mu       sync.Mutex
+	expired  bool
+}
+
+func (c *clockDeadlineCtx) Deadline() (time.Time, bool) {
+	if parent, ok := c.Context.Deadline(); ok && parent.Before(c.deadline) {
+		return parent, true
+	}
+	return c.deadline, true
+}
+
+func (c *clockDeadlineCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.expired {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+// ManualClock is a Clock that only moves when Advance is called
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+// NewManualClock returns a clock frozen at now
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) func() bool {
+	if d <= 0 {
+		go f()
+		return func() bool { return false }
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &manualTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		wasPending := !t.stopped
+		t.stopped = true
+		return wasPending
+	}
+}
+
+// Advance moves the clock forward by d and, before returning, runs every
+// function scheduled to fire by then in deadline order
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var due []*manualTimer
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		switch {
+		case t.stopped:
+		case !t.at.After(c.now):
+			t.stopped = true
+			due = append(due, t)
+		default:
+			pending = append(pending, t)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// Pending reports how many scheduled functions have not yet fired, so a test
+// can wait for the client to start waiting before advancing
+func (c *ManualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if !t.stopped {
+			n++
+		}
+	}
+	return n
+}
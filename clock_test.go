@@ -0,0 +1,95 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitTimer blocks until a pending timer on clock is due d from now, so a
+// test advances only once the wait it means to end has been scheduled
+func waitTimer(t *testing.T, clock *ManualClock, d time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !clock.hasTimerAt(clock.Now().Add(d)) {
+		if time.Now().After(deadline) {
+			t.Fatalf("nothing waited %v on the clock", d)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *ManualClock) hasTimerAt(at time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range c.timers {
+		if !t.stopped && t.at.Equal(at) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestResponseCacheExpiresOnClientClock(t *testing.T) {
+	var calls atomic.Int32
+	srv := stubEL(t, func(string, []json.RawMessage) (interface{}, *RPCError) {
+		calls.Add(1)
+		return map[string]interface{}{"hash": Hash{1}}, nil
+	})
+	clock := NewManualClock(time.Unix(1700000000, 0))
+	c := NewEngineClient(srv.URL, nil, WithoutAuth(), WithClock(clock),
+		WithResponseCache(8, map[string]time.Duration{"eth_getBlockByHash": time.Minute}))
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.GetBlockByHash(ctx, Hash{1}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("EL called %d times before expiry, want 1", got)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, err := c.GetBlockByHash(ctx, Hash{1}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("EL called %d times after expiry, want 2", got)
+	}
+}
+
+func TestNewPayloadAndWaitBacksOffOnClientClock(t *testing.T) {
+	var calls atomic.Int32
+	srv := stubEL(t, func(string, []json.RawMessage) (interface{}, *RPCError) {
+		if calls.Add(1) == 1 {
+			return PayloadStatus{Status: StatusSyncing}, nil
+		}
+		return PayloadStatus{Status: StatusValid}, nil
+	})
+	clock := NewManualClock(time.Unix(1700000000, 0))
+	c := NewEngineClient(srv.URL, nil, WithoutAuth(), WithClock(clock))
+
+	done := make(chan *PayloadStatus, 1)
+	go func() {
+		status, err := c.NewPayloadAndWait(context.Background(), map[string]interface{}{"blockHash": Hash{1}})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- status
+	}()
+	waitTimer(t, clock, awaitInitialBackoff)
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("resubmitted before the backoff elapsed: %d calls", got)
+	}
+	clock.Advance(awaitInitialBackoff)
+	select {
+	case status := <-done:
+		if status == nil || status.Status != StatusValid {
+			t.Fatalf("got %+v, want VALID", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("NewPayloadAndWait still waiting after the backoff elapsed")
+	}
+}
@@ -0,0 +1,9 @@
+// Command engine-client talks to an execution client's Engine API; see the
+// engineclient package for the subcommands and settings
+package main
+
+import engineclient "github.com/devlongs/engine-client"
+
+func main() {
+	engineclient.Main()
+}
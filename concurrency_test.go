@@ -0,0 +1,48 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// benchmarkNewPayload submits newPayload calls to a stub EL that answers
+// VALID at once, so the numbers measure the client's own overhead: token
+// signing, id allocation, encoding and connection reuse
+func benchmarkNewPayload(b *testing.B, parallel bool) {
+	srv := stubELServer(func(string, []json.RawMessage) (interface{}, *RPCError) {
+		return PayloadStatus{Status: StatusValid}, nil
+	})
+	defer srv.Close()
+	c := NewEngineClient(srv.URL, []byte("0123456789abcdef0123456789abcdef"))
+	defer c.Close()
+	payload := map[string]interface{}{"blockHash": Hash{1}}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if !parallel {
+		for i := 0; i < b.N; i++ {
+			if _, err := c.NewPayload(ctx, payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+		return
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.NewPayload(ctx, payload); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkNewPayload(b *testing.B) {
+	benchmarkNewPayload(b, false)
+}
+
+func BenchmarkNewPayloadParallel(b *testing.B) {
+	benchmarkNewPayload(b, true)
+}
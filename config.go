@@ -0,0 +1,382 @@
+package engineclient
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config is the connection configuration shared by every command. Each
+// setting is resolved from, highest precedence first, a command-line flag,
+// an environment variable, the config file and a built-in default.
+type Config struct {
+	Endpoint    string
+	JWTPath     string
+	JWTSecret   string
+	JWTAuditLog string
+	Proxy       string
+	PinIP       string
+	KeepAlive   string
+	Network     string
+	Fork        string
+
+	// path is the config file that was read, if any
+	path string
+	// sources records where each setting's value came from, by key
+	sources map[string]string
+}
+
+// setting binds one Config field to its config file key, flag and
+// environment variables. Settings without a flag can only come from the
+// environment or the file, which keeps secrets out of process listings.
+type setting struct {
+	key    string
+	flag   string
+	env    []string
+	def    string
+	usage  string
+	secret bool
+	field  func(*Config) *string
+}
+
+// settings lists every configurable value. The first environment variable
+// is the canonical name; later ones are the older names, still honoured.
+var settings = []setting{
+	{
+		key: "endpoint", flag: "endpoint", env: []string{"ENGINE_CLIENT_ENDPOINT"}, def: defaultEndpoint,
+		usage: "engine API endpoint",
+		field: func(c *Config) *string { return &c.Endpoint },
+	},
+	{
+		key: "jwtPath", flag: "jwt-path", env: []string{"ENGINE_CLIENT_JWT_PATH", "JWT_SECRET_FILE"},
+		usage: "JWT secret file, re-read whenever it changes",
+		field: func(c *Config) *string { return &c.JWTPath },
+	},
+	{
+		key: "jwtSecret", env: []string{"ENGINE_CLIENT_JWT_SECRET", "JWT_SECRET"}, secret: true,
+		usage: "JWT secret, used when no secret file is set",
+		field: func(c *Config) *string { return &c.JWTSecret },
+	},
+	{
+		key: "jwtAuditLog", env: []string{"ENGINE_CLIENT_JWT_AUDIT_LOG", "JWT_AUDIT_LOG"},
+		usage: "file to record every token sent to",
+		field: func(c *Config) *string { return &c.JWTAuditLog },
+	},
+	{
+		key: "proxy", flag: "proxy", env: []string{"ENGINE_CLIENT_PROXY", "ENGINE_PROXY"},
+		usage: "socks5:// or http:// proxy to reach the EL through",
+		field: func(c *Config) *string { return &c.Proxy },
+	},
+	{
+		key: "pinIP", flag: "pin-ip", env: []string{"ENGINE_CLIENT_PIN_IP"},
+		usage: "address to connect to instead of resolving the endpoint's host",
+		field: func(c *Config) *string { return &c.PinIP },
+	},
+	{
+		key: "keepAlive", flag: "keep-alive", env: []string{"ENGINE_CLIENT_KEEP_ALIVE"},
+		usage: "probe the EL after this long without a call, e.g. 30s",
+		field: func(c *Config) *string { return &c.KeepAlive },
+	},
+	{
+		key: "network", env: []string{"ENGINE_CLIENT_NETWORK", "ENGINE_NETWORK"},
+		usage: "network forkchoice updates must be sent on",
+		field: func(c *Config) *string { return &c.Network },
+	},
+	{
+		// No flag, since simulate and bench take their own -fork.
+		key: "fork", env: []string{"ENGINE_CLIENT_FORK"},
+		usage: "fork the EL runs, which picks forkchoiceUpdated versions",
+		field: func(c *Config) *string { return &c.Fork },
+	},
+}
+
+// configFileEnv names the config file when -config is not passed
+const configFileEnv = "ENGINE_CLIENT_CONFIG"
+
+// defaultConfigPath is read when it exists and no file is named explicitly
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "engine-client", "config.json")
+}
+
+// configFlags holds a command's flags for the shared settings
+type configFlags struct {
+	fs     *flag.FlagSet
+	path   *string
+	values map[string]*string
+}
+
+// addConfigFlags registers -config and the flag of every setting that has
+// one. The flags default to empty so resolve can tell which were passed.
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	f := &configFlags{
+		fs:     fs,
+		path:   fs.String("config", "", "config file (defaults to "+configFileEnv+", then "+defaultConfigPath()+" if it exists)"),
+		values: make(map[string]*string),
+	}
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		usage := s.usage
+		if s.def != "" {
+			usage += fmt.Sprintf(" (default %q)", s.def)
+		}
+		f.values[s.key] = fs.String(s.flag, "", usage)
+	}
+	return f
+}
+
+// resolve builds the effective configuration once the flag set is parsed
+func (f *configFlags) resolve() (*Config, error) {
+	passed := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { passed[fl.Name] = true })
+	flags := make(map[string]string)
+	for _, s := range settings {
+		if s.flag != "" && passed[s.flag] {
+			flags[s.key] = *f.values[s.key]
+		}
+	}
+	path := ""
+	if passed["config"] {
+		path = *f.path
+	}
+	return loadConfig(path, flags)
+}
+
+// loadConfig resolves every setting from flags, the environment, the config
+// file at path and the defaults. An empty path falls back to configFileEnv
+// and then defaultConfigPath, which may be missing. When the settings fail
+// validation the Config is still returned, so it can be printed.
+func loadConfig(path string, flags map[string]string) (*Config, error) {
+	cfg := &Config{sources: make(map[string]string)}
+	for _, s := range settings {
+		if s.def != "" {
+			*s.field(cfg) = s.def
+			cfg.sources[s.key] = "default"
+		}
+	}
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(configFileEnv)
+		explicit = path != ""
+	}
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path != "" {
+		values, err := readConfigFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+		case err != nil:
+			return nil, err
+		default:
+			cfg.path = path
+			for _, s := range settings {
+				if v, ok := values[s.key]; ok {
+					*s.field(cfg) = v
+					cfg.sources[s.key] = "file"
+				}
+			}
+		}
+	}
+
+	for _, s := range settings {
+		for _, name := range s.env {
+			if v := os.Getenv(name); v != "" {
+				*s.field(cfg) = v
+				cfg.sources[s.key] = "env " + name
+				break
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := flags[s.key]; ok {
+			*s.field(cfg) = v
+			cfg.sources[s.key] = "flag -" + s.flag
+		}
+	}
+	return cfg, cfg.validate()
+}
+
+// readConfigFile reads a JSON object of setting keys to string values,
+// rejecting keys that name no setting so typos do not go unnoticed
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("config file %s: %v", path, err)
+	}
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.key] = true
+	}
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if !known[key] {
+			return nil, fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, fmt.Errorf("config file %s: %s must be a string", path, key)
+		}
+		values[key] = s
+	}
+	return values, nil
+}
+
+// validate checks every setting, reporting all problems at once
+func (c *Config) validate() error {
+	var problems []string
+	if c.Endpoint == "" {
+		problems = append(problems, "endpoint is required")
+	} else if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("endpoint %q is not an http or https URL", c.Endpoint))
+	}
+	if c.JWTPath != "" {
+		if _, err := os.Stat(c.JWTPath); err != nil {
+			problems = append(problems, fmt.Sprintf("jwtPath: %v", err))
+		}
+	}
+	if c.Proxy != "" {
+		if _, err := parseProxyURL(c.Proxy); err != nil {
+			problems = append(problems, fmt.Sprintf("proxy: %v", err))
+		}
+	}
+	if c.PinIP != "" && net.ParseIP(c.PinIP) == nil {
+		problems = append(problems, fmt.Sprintf("pinIP %q is not an IP address", c.PinIP))
+	}
+	if c.KeepAlive != "" {
+		if d, err := time.ParseDuration(c.KeepAlive); err != nil || d <= 0 {
+			problems = append(problems, fmt.Sprintf("keepAlive %q is not a positive duration", c.KeepAlive))
+		}
+	}
+	if c.Network != "" {
+		if _, err := ParseNetwork(c.Network); err != nil {
+			problems = append(problems, fmt.Sprintf("network: %v", err))
+		}
+	}
+	if c.Fork != "" {
+		if _, err := ParseFork(c.Fork); err != nil {
+			problems = append(problems, fmt.Sprintf("fork: %v", err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// newClient builds a client from the configuration, falling back to
+// unauthenticated requests for dev endpoints run with auth disabled
+func (c *Config) newClient(opts ...Option) (*EngineClient, error) {
+	if c.Network != "" {
+		network, err := ParseNetwork(c.Network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %v", err)
+		}
+		opts = append(opts, WithChainVerification(network.ChainID, network.GenesisHash))
+	}
+	if c.Fork != "" {
+		fork, err := ParseFork(c.Fork)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fork: %v", err)
+		}
+		opts = append(opts, WithFork(fork))
+	}
+	if c.Proxy != "" {
+		opts = append(opts, WithProxy(c.Proxy))
+	}
+	if c.PinIP != "" {
+		opts = append(opts, WithPinnedIP(c.PinIP))
+	}
+	if c.KeepAlive != "" {
+		interval, err := time.ParseDuration(c.KeepAlive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keepAlive: %v", err)
+		}
+		opts = append(opts, WithKeepAlive(interval, func(err error) {
+			fmt.Fprintf(os.Stderr, "keep-alive probe failed: %v\n", err)
+		}))
+	}
+	if c.JWTAuditLog != "" {
+		opts = append(opts, WithJWTAuditLog(c.JWTAuditLog, defaultAuditMaxSize, defaultAuditMaxBackups))
+	}
+	if c.JWTPath != "" {
+		return NewEngineClientWithSecretFile(c.Endpoint, c.JWTPath, defaultSecretPollInterval, opts...)
+	}
+	if c.JWTSecret == "" {
+		fmt.Fprintln(os.Stderr, "no JWT secret configured, sending unauthenticated requests")
+		opts = append(opts, WithoutAuth())
+	}
+	return NewEngineClient(c.Endpoint, []byte(c.JWTSecret), opts...), nil
+}
+
+// runConfig dispatches the config subcommands
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: config print")
+	}
+	switch args[0] {
+	case "print":
+		return runConfigPrint(args[1:])
+	default:
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+}
+
+// runConfigPrint shows the effective value of every setting and where it
+// came from, then fails if the configuration is invalid
+func runConfigPrint(args []string) error {
+	fs := flag.NewFlagSet("config print", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	defer out.batch()()
+
+	cfg, err := config.resolve()
+	if cfg == nil {
+		return err
+	}
+	if cfg.path != "" {
+		out.note("# config file %s", cfg.path)
+	}
+	for _, s := range settings {
+		value := *s.field(cfg)
+		if s.secret && value != "" {
+			value = "<redacted>"
+		}
+		source := cfg.sources[s.key]
+		if source == "" {
+			source = "unset"
+		}
+		out.emit(record{{"key", s.key}, {"value", value}, {"source", source}}, func(w io.Writer) {
+			if value == "" {
+				fmt.Fprintf(w, "%-12s (%s)\n", s.key, source)
+				return
+			}
+			fmt.Fprintf(w, "%-12s %s (%s)\n", s.key, value, source)
+		})
+	}
+	return err
+}
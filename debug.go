@@ -0,0 +1,89 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// BadBlock is an entry of debug_getBadBlocks: a block the EL rejected, with
+// its decoded form and raw RLP
+type BadBlock struct {
+	Hash  Hash                   `json:"hash"`
+	Block map[string]interface{} `json:"block"`
+	RLP   string                 `json:"rlp"`
+}
+
+// GetBadBlocks returns the EL's record of recently rejected blocks
+func (c *EngineClient) GetBadBlocks(ctx context.Context) ([]BadBlock, error) {
+	response, err := c.makeRequest(ctx, "debug_getBadBlocks", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	var blocks []BadBlock
+	if err := decodeResult(response, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// FindBadBlock looks up the bad-block record for hash, for use right after
+// newPayload returns INVALID. It returns nil if the EL has no record of it.
+func (c *EngineClient) FindBadBlock(ctx context.Context, hash Hash) (*BadBlock, error) {
+	blocks, err := c.GetBadBlocks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range blocks {
+		if blocks[i].Hash == hash {
+			return &blocks[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetRawBlock returns the RLP encoding of a block. block is a hash, a hex
+// block number or a tag such as "latest".
+func (c *EngineClient) GetRawBlock(ctx context.Context, block string) ([]byte, error) {
+	return c.rawDebugCall(ctx, "debug_getRawBlock", block)
+}
+
+// GetRawHeader returns the RLP encoding of a block header
+func (c *EngineClient) GetRawHeader(ctx context.Context, block string) ([]byte, error) {
+	return c.rawDebugCall(ctx, "debug_getRawHeader", block)
+}
+
+// GetRawReceipts returns the consensus encoding of each receipt in a block
+func (c *EngineClient) GetRawReceipts(ctx context.Context, block string) ([][]byte, error) {
+	response, err := c.makeRequest(ctx, "debug_getRawReceipts", []interface{}{block})
+	if err != nil {
+		return nil, err
+	}
+	var encoded []string
+	if err := decodeResult(response, &encoded); err != nil {
+		return nil, err
+	}
+	receipts := make([][]byte, len(encoded))
+	for i, r := range encoded {
+		if receipts[i], err = decodeHex(r); err != nil {
+			return nil, fmt.Errorf("receipt %d: %v", i, err)
+		}
+	}
+	return receipts, nil
+}
+
+func (c *EngineClient) rawDebugCall(ctx context.Context, method, block string) ([]byte, error) {
+	response, err := c.makeRequest(ctx, method, []interface{}{block})
+	if err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := decodeResult(response, &raw); err != nil {
+		return nil, err
+	}
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, fmt.Errorf("%s returned %s, want a hex string", method, raw)
+	}
+	return decodeHex(encoded)
+}
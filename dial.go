@@ -0,0 +1,160 @@
+package engineclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// defaultFallbackDelay is how long a connection attempt gets before the next
+// address is tried alongside it, as recommended by RFC 8305
+const defaultFallbackDelay = 250 * time.Millisecond
+
+// endpointDialer connects to a host by racing every address it resolves to.
+// IPv6 and IPv4 addresses are interleaved, and each attempt starts when the
+// previous one fails or after fallbackDelay, whichever comes first, so a
+// dead address on a dual-stack or round-robin deployment costs a fraction of
+// a second rather than a full connect timeout.
+type endpointDialer struct {
+	dialer        net.Dialer
+	resolver      *net.Resolver
+	fallbackDelay time.Duration
+	// pins maps a hostname to the address used for it instead of resolving
+	pins   map[string]net.IP
+	pinErr error
+}
+
+func newEndpointDialer() *endpointDialer {
+	return &endpointDialer{
+		dialer:        net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		resolver:      net.DefaultResolver,
+		fallbackDelay: defaultFallbackDelay,
+		pins:          make(map[string]net.IP),
+	}
+}
+
+// DialContext has the signature http.Transport expects
+func (d *endpointDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.pinErr != nil {
+		return nil, d.pinErr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+	if ip, ok := d.pins[host]; ok {
+		return d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+	}
+
+	resolved, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	ips := interleaveFamilies(resolved, network)
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no %s addresses for %s", network, host)
+	}
+	return d.race(ctx, network, port, ips)
+}
+
+// race runs staggered connection attempts to ips and returns the first to
+// succeed, closing any that complete after it
+func (d *endpointDialer) race(ctx context.Context, network, port string, ips []net.IP) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type attempt struct {
+		conn net.Conn
+		ip   net.IP
+		err  error
+	}
+	results := make(chan attempt, len(ips))
+	next, pending := 0, 0
+	start := func() {
+		ip := ips[next]
+		next++
+		pending++
+		go func() {
+			conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			results <- attempt{conn, ip, err}
+		}()
+	}
+	start()
+	timer := time.NewTimer(d.fallbackDelay)
+	defer timer.Stop()
+
+	var failures []string
+	var last error
+	for pending > 0 {
+		select {
+		case a := <-results:
+			pending--
+			if a.err == nil {
+				go func(n int) {
+					for ; n > 0; n-- {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				return a.conn, nil
+			}
+			if len(ips) == 1 {
+				return nil, a.err
+			}
+			if last != nil {
+				failures = append(failures, last.Error())
+			}
+			last = fmt.Errorf("%s: %w", a.ip, a.err)
+			if next < len(ips) {
+				start()
+				timer.Reset(d.fallbackDelay)
+			}
+		case <-timer.C:
+			if next < len(ips) {
+				start()
+				timer.Reset(d.fallbackDelay)
+			}
+		}
+	}
+	return nil, fmt.Errorf("all %d addresses failed: %s; %w", len(ips), strings.Join(failures, "; "), last)
+}
+
+// interleaveFamilies orders addresses alternately from each family, starting
+// with the family of the first address the resolver returned, and drops
+// those the network cannot use
+func interleaveFamilies(addrs []net.IPAddr, network string) []net.IP {
+	var v4, v6 []net.IP
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			v4 = append(v4, a.IP)
+		} else {
+			v6 = append(v6, a.IP)
+		}
+	}
+	switch network {
+	case "tcp4":
+		return v4
+	case "tcp6":
+		return v6
+	}
+	first, second := v6, v4
+	if len(addrs) > 0 && addrs[0].IP.To4() != nil {
+		first, second = v4, v6
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ips = append(ips, first[i])
+		}
+		if i < len(second) {
+			ips = append(ips, second[i])
+		}
+	}
+	return ips
+}
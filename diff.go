@@ -0,0 +1,254 @@
+package engineclient
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// PayloadBody is one entry of a getPayloadBodiesByHash or ByRange response
+type PayloadBody struct {
+	Transactions []string     `json:"transactions"`
+	Withdrawals  []Withdrawal `json:"withdrawals"`
+}
+
+// PayloadDifference is a single field that differs between two payloads
+type PayloadDifference struct {
+	Field string
+	A     string
+	B     string
+}
+
+func (d PayloadDifference) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Field, d.A, d.B)
+}
+
+// missingValue stands in for a list element only one side has
+const missingValue = "<missing>"
+
+// DiffPayloads compares two payloads field by field. Transactions are
+// compared by their raw encoding and reported by hash; withdrawals are
+// compared per field.
+func DiffPayloads(a, b *ExecutionPayload) []PayloadDifference {
+	var diffs []PayloadDifference
+	field := func(name, x, y string) {
+		if x != y {
+			diffs = append(diffs, PayloadDifference{Field: name, A: x, B: y})
+		}
+	}
+	field("parentHash", a.ParentHash.Hex(), b.ParentHash.Hex())
+	field("feeRecipient", a.FeeRecipient.Hex(), b.FeeRecipient.Hex())
+	field("stateRoot", a.StateRoot.Hex(), b.StateRoot.Hex())
+	field("receiptsRoot", a.ReceiptsRoot.Hex(), b.ReceiptsRoot.Hex())
+	field("logsBloom", a.LogsBloom, b.LogsBloom)
+	field("prevRandao", a.PrevRandao.Hex(), b.PrevRandao.Hex())
+	field("blockNumber", a.BlockNumber, b.BlockNumber)
+	field("gasLimit", a.GasLimit, b.GasLimit)
+	field("gasUsed", a.GasUsed, b.GasUsed)
+	field("timestamp", a.Timestamp, b.Timestamp)
+	field("extraData", a.ExtraData, b.ExtraData)
+	field("baseFeePerGas", a.BaseFeePerGas, b.BaseFeePerGas)
+	field("blockHash", a.BlockHash.Hex(), b.BlockHash.Hex())
+	field("blobGasUsed", optionalQuantity(a.BlobGasUsed), optionalQuantity(b.BlobGasUsed))
+	field("excessBlobGas", optionalQuantity(a.ExcessBlobGas), optionalQuantity(b.ExcessBlobGas))
+
+	body := DiffPayloadBody(a, &PayloadBody{Transactions: b.Transactions, Withdrawals: b.Withdrawals})
+	return append(diffs, body...)
+}
+
+// DiffPayloadBody compares the transactions and withdrawals of a payload
+// against a body returned by getPayloadBodies
+func DiffPayloadBody(p *ExecutionPayload, body *PayloadBody) []PayloadDifference {
+	var diffs []PayloadDifference
+	if len(p.Transactions) != len(body.Transactions) {
+		diffs = append(diffs, PayloadDifference{
+			Field: "transactions.length",
+			A:     fmt.Sprint(len(p.Transactions)),
+			B:     fmt.Sprint(len(body.Transactions)),
+		})
+	}
+	for i := 0; i < len(p.Transactions) || i < len(body.Transactions); i++ {
+		x, y := listItem(p.Transactions, i), listItem(body.Transactions, i)
+		if x != y {
+			diffs = append(diffs, PayloadDifference{
+				Field: fmt.Sprintf("transactions[%d]", i),
+				A:     transactionLabel(x),
+				B:     transactionLabel(y),
+			})
+		}
+	}
+
+	if (p.Withdrawals == nil) != (body.Withdrawals == nil) {
+		diffs = append(diffs, PayloadDifference{
+			Field: "withdrawals",
+			A:     withdrawalsLabel(p.Withdrawals),
+			B:     withdrawalsLabel(body.Withdrawals),
+		})
+		return diffs
+	}
+	if len(p.Withdrawals) != len(body.Withdrawals) {
+		diffs = append(diffs, PayloadDifference{
+			Field: "withdrawals.length",
+			A:     fmt.Sprint(len(p.Withdrawals)),
+			B:     fmt.Sprint(len(body.Withdrawals)),
+		})
+	}
+	for i := 0; i < len(p.Withdrawals) && i < len(body.Withdrawals); i++ {
+		x, y := p.Withdrawals[i], body.Withdrawals[i]
+		prefix := fmt.Sprintf("withdrawals[%d].", i)
+		for _, f := range []struct{ name, a, b string }{
+			{"index", x.Index, y.Index},
+			{"validatorIndex", x.ValidatorIndex, y.ValidatorIndex},
+			{"address", x.Address.Hex(), y.Address.Hex()},
+			{"amount", x.Amount, y.Amount},
+		} {
+			if f.a != f.b {
+				diffs = append(diffs, PayloadDifference{Field: prefix + f.name, A: f.a, B: f.b})
+			}
+		}
+	}
+	return diffs
+}
+
+func optionalQuantity(q *string) string {
+	if q == nil {
+		return missingValue
+	}
+	return *q
+}
+
+func listItem(list []string, i int) string {
+	if i < len(list) {
+		return list[i]
+	}
+	return missingValue
+}
+
+// transactionLabel identifies a raw transaction by its hash and size rather
+// than printing the full encoding
+func transactionLabel(tx string) string {
+	if tx == missingValue {
+		return tx
+	}
+	raw, err := decodeHex(tx)
+	if err != nil {
+		return fmt.Sprintf("invalid (%v)", err)
+	}
+	return fmt.Sprintf("%s (%d bytes)", Hash(keccak256(raw)), len(raw))
+}
+
+func withdrawalsLabel(w []Withdrawal) string {
+	if w == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%d withdrawals", len(w))
+}
+
+// loadPayloadFile reads a payload or payload body from a file holding it
+// bare, as the executionPayload of a getPayload envelope, or as the result
+// of a JSON-RPC response. A list of bodies is indexed with index.
+func loadPayloadFile(path string, index int) (*ExecutionPayload, *PayloadBody, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, key := range []string{"result", "executionPayload"} {
+		if obj, ok := value.(map[string]interface{}); ok && obj[key] != nil {
+			value = obj[key]
+		}
+	}
+	if list, ok := value.([]interface{}); ok {
+		if index < 0 || index >= len(list) {
+			return nil, nil, fmt.Errorf("%s: no body at index %d", path, index)
+		}
+		value = list[index]
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: not a payload or payload body", path)
+	}
+	if _, ok := obj["blockHash"]; ok {
+		p, err := DecodeExecutionPayload(obj)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return p, nil, nil
+	}
+	raw, _ := json.Marshal(obj)
+	var body PayloadBody
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, nil, fmt.Errorf("%s: failed to decode payload body: %v", path, err)
+	}
+	return nil, &body, nil
+}
+
+// runPayload dispatches the payload subcommands
+func runPayload(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: payload diff <a.json> <b.json>")
+	}
+	switch args[0] {
+	case "diff":
+		return runPayloadDiff(args[1:])
+	default:
+		return fmt.Errorf("unknown payload command %q", args[0])
+	}
+}
+
+// runPayloadDiff prints every field that differs between two payloads, or
+// between a payload and a payload body, and fails if there are any
+func runPayloadDiff(args []string) error {
+	fs := flag.NewFlagSet("payload diff", flag.ExitOnError)
+	index := fs.Int("index", 0, "entry to use when a file holds a list of payload bodies")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: payload diff [-index N] <a.json> <b.json>")
+	}
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	defer out.batch()()
+
+	pa, ba, err := loadPayloadFile(fs.Arg(0), *index)
+	if err != nil {
+		return err
+	}
+	pb, bb, err := loadPayloadFile(fs.Arg(1), *index)
+	if err != nil {
+		return err
+	}
+
+	var diffs []PayloadDifference
+	switch {
+	case pa != nil && pb != nil:
+		diffs = DiffPayloads(pa, pb)
+	case pa != nil:
+		diffs = DiffPayloadBody(pa, bb)
+	case pb != nil:
+		// Keep the output's A and B columns in argument order.
+		for _, d := range DiffPayloadBody(pb, ba) {
+			diffs = append(diffs, PayloadDifference{Field: d.Field, A: d.B, B: d.A})
+		}
+	default:
+		return fmt.Errorf("at least one file must hold a full execution payload")
+	}
+
+	for _, d := range diffs {
+		out.emit(record{{"field", d.Field}, {"a", d.A}, {"b", d.B}}, func(w io.Writer) {
+			fmt.Fprintln(w, d)
+		})
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("payloads differ in %d fields", len(diffs))
+	}
+	out.note("payloads match")
+	return nil
+}
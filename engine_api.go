@@ -0,0 +1,59 @@
+package engineclient
+
+import (
+	"context"
+	"iter"
+	"net/http"
+)
+
+// EngineAPI is the full method set of EngineClient, for code that wants to
+// depend on an interface: to wrap the client with its own middleware or to
+// substitute an enginetest.MockEngineAPI in tests.
+type EngineAPI interface {
+	NewPayload(ctx context.Context, payload map[string]interface{}) (map[string]interface{}, error)
+	NewPayloadV4(ctx context.Context, payload map[string]interface{}, versionedHashes []Hash, parentBeaconBlockRoot Hash, executionRequests []string) (map[string]interface{}, error)
+	NewPayloadAndWait(ctx context.Context, payload map[string]interface{}) (*PayloadStatus, error)
+	ForkchoiceUpdated(ctx context.Context, state ForkChoiceState, attributes *PayloadAttributes) (map[string]interface{}, error)
+	ResumeForkchoice(ctx context.Context) (*ForkChoiceState, map[string]interface{}, error)
+	RewindToLatestValid(ctx context.Context, invalid Hash, status PayloadStatus, current ForkChoiceState) (*RewindEvent, error)
+	GetPayload(ctx context.Context, payloadID string) (map[string]interface{}, error)
+	GetPayloadV4(ctx context.Context, payloadID string) (map[string]interface{}, error)
+	GetPayloadBodiesByHash(ctx context.Context, hashes []Hash) (map[string]interface{}, error)
+	GetPayloadBodiesByRange(ctx context.Context, start, count uint64) (map[string]interface{}, error)
+	StreamPayloadBodiesByRange(ctx context.Context, start, count, pageSize uint64) iter.Seq2[RangeBody, error]
+	ExchangeTransitionConfiguration(ctx context.Context, config TransitionConfiguration) (map[string]interface{}, error)
+	GetClientVersion(ctx context.Context, version ClientVersion) (map[string]interface{}, error)
+	CheckCapabilities(ctx context.Context, forks ...Fork) (*CapabilityReport, error)
+
+	GetBlockByHash(ctx context.Context, hash Hash, fullTransactions bool) (map[string]interface{}, error)
+	GetBlockByNumber(ctx context.Context, number string, fullTransactions bool) (map[string]interface{}, error)
+	HeadBlock(ctx context.Context) (*HeadBlock, error)
+	SyncStatus(ctx context.Context) (*SyncProgress, error)
+	ChainID(ctx context.Context) (uint64, error)
+	GenesisHash(ctx context.Context) (Hash, error)
+	VerifyChain(ctx context.Context, expectedChainID uint64, expectedGenesisHash Hash) error
+
+	GetBadBlocks(ctx context.Context) ([]BadBlock, error)
+	FindBadBlock(ctx context.Context, hash Hash) (*BadBlock, error)
+	GetRawBlock(ctx context.Context, block string) ([]byte, error)
+	GetRawHeader(ctx context.Context, block string) ([]byte, error)
+	GetRawReceipts(ctx context.Context, block string) ([][]byte, error)
+
+	ImportPayloads(ctx context.Context, payloads <-chan ImportPayload, cfg ImportConfig) (*ImportReport, error)
+	FuzzPayload(ctx context.Context, item ImportPayload, mutations []PayloadMutation, baseline bool, onResult func(FuzzResult)) (*FuzzReport, error)
+	FollowHeads(ctx context.Context, wsURL string, cfg FollowerConfig) error
+	Simulate(ctx context.Context, cfg SimulatorConfig) error
+	RunScenario(ctx context.Context, s *Scenario, keepGoing bool, onStep func(StepResult)) *ScenarioReport
+
+	Call(ctx context.Context, method string, params interface{}) (map[string]interface{}, error)
+	CallMethod(ctx context.Context, family MethodFamily, fork Fork, args MethodArgs) (map[string]interface{}, error)
+
+	HeadHistory() []Hash
+	Status() StatusReport
+	Readiness(ctx context.Context, forks ...Fork) ReadinessReport
+	StatusHandler(forks ...Fork) http.Handler
+	Snapshot(ctx context.Context, forks ...Fork) *WatchSnapshot
+	Close() error
+}
+
+var _ EngineAPI = (*EngineClient)(nil)
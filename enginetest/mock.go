@@ -0,0 +1,390 @@
+// Package enginetest provides test doubles for code built on the
+// engineclient package.
+package enginetest
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"sync"
+
+	engineclient "github.com/devlongs/engine-client"
+)
+
+// MockEngineAPI is an engineclient.EngineAPI for tests. Each method calls
+// the matching Func field when it is set and otherwise does nothing,
+// returning zero values and a nil error; the methods without an error
+// return an empty report, an empty iterator or a handler answering 404, so
+// they are safe to use unset. Calls returns the names of the methods called
+// so far, in order.
+type MockEngineAPI struct {
+	NewPayloadFunc                      func(ctx context.Context, payload map[string]interface{}) (map[string]interface{}, error)
+	NewPayloadV4Func                    func(ctx context.Context, payload map[string]interface{}, versionedHashes []engineclient.Hash, parentBeaconBlockRoot engineclient.Hash, executionRequests []string) (map[string]interface{}, error)
+	NewPayloadAndWaitFunc               func(ctx context.Context, payload map[string]interface{}) (*engineclient.PayloadStatus, error)
+	ForkchoiceUpdatedFunc               func(ctx context.Context, state engineclient.ForkChoiceState, attributes *engineclient.PayloadAttributes) (map[string]interface{}, error)
+	ResumeForkchoiceFunc                func(ctx context.Context) (*engineclient.ForkChoiceState, map[string]interface{}, error)
+	RewindToLatestValidFunc             func(ctx context.Context, invalid engineclient.Hash, status engineclient.PayloadStatus, current engineclient.ForkChoiceState) (*engineclient.RewindEvent, error)
+	GetPayloadFunc                      func(ctx context.Context, payloadID string) (map[string]interface{}, error)
+	GetPayloadV4Func                    func(ctx context.Context, payloadID string) (map[string]interface{}, error)
+	GetPayloadBodiesByHashFunc          func(ctx context.Context, hashes []engineclient.Hash) (map[string]interface{}, error)
+	GetPayloadBodiesByRangeFunc         func(ctx context.Context, start, count uint64) (map[string]interface{}, error)
+	StreamPayloadBodiesByRangeFunc      func(ctx context.Context, start, count, pageSize uint64) iter.Seq2[engineclient.RangeBody, error]
+	ExchangeTransitionConfigurationFunc func(ctx context.Context, config engineclient.TransitionConfiguration) (map[string]interface{}, error)
+	GetClientVersionFunc                func(ctx context.Context, version engineclient.ClientVersion) (map[string]interface{}, error)
+	CheckCapabilitiesFunc               func(ctx context.Context, forks ...engineclient.Fork) (*engineclient.CapabilityReport, error)
+	GetBlockByHashFunc                  func(ctx context.Context, hash engineclient.Hash, fullTransactions bool) (map[string]interface{}, error)
+	GetBlockByNumberFunc                func(ctx context.Context, number string, fullTransactions bool) (map[string]interface{}, error)
+	HeadBlockFunc                       func(ctx context.Context) (*engineclient.HeadBlock, error)
+	SyncStatusFunc                      func(ctx context.Context) (*engineclient.SyncProgress, error)
+	ChainIDFunc                         func(ctx context.Context) (uint64, error)
+	GenesisHashFunc                     func(ctx context.Context) (engineclient.Hash, error)
+	VerifyChainFunc                     func(ctx context.Context, expectedChainID uint64, expectedGenesisHash engineclient.Hash) error
+	GetBadBlocksFunc                    func(ctx context.Context) ([]engineclient.BadBlock, error)
+	FindBadBlockFunc                    func(ctx context.Context, hash engineclient.Hash) (*engineclient.BadBlock, error)
+	GetRawBlockFunc                     func(ctx context.Context, block string) ([]byte, error)
+	GetRawHeaderFunc                    func(ctx context.Context, block string) ([]byte, error)
+	GetRawReceiptsFunc                  func(ctx context.Context, block string) ([][]byte, error)
+	ImportPayloadsFunc                  func(ctx context.Context, payloads <-chan engineclient.ImportPayload, cfg engineclient.ImportConfig) (*engineclient.ImportReport, error)
+	FuzzPayloadFunc                     func(ctx context.Context, item engineclient.ImportPayload, mutations []engineclient.PayloadMutation, baseline bool, onResult func(engineclient.FuzzResult)) (*engineclient.FuzzReport, error)
+	FollowHeadsFunc                     func(ctx context.Context, wsURL string, cfg engineclient.FollowerConfig) error
+	SimulateFunc                        func(ctx context.Context, cfg engineclient.SimulatorConfig) error
+	RunScenarioFunc                     func(ctx context.Context, s *engineclient.Scenario, keepGoing bool, onStep func(engineclient.StepResult)) *engineclient.ScenarioReport
+	CallFunc                            func(ctx context.Context, method string, params interface{}) (map[string]interface{}, error)
+	CallMethodFunc                      func(ctx context.Context, family engineclient.MethodFamily, fork engineclient.Fork, args engineclient.MethodArgs) (map[string]interface{}, error)
+	HeadHistoryFunc                     func() []engineclient.Hash
+	StatusFunc                          func() engineclient.StatusReport
+	ReadinessFunc                       func(ctx context.Context, forks ...engineclient.Fork) engineclient.ReadinessReport
+	StatusHandlerFunc                   func(forks ...engineclient.Fork) http.Handler
+	SnapshotFunc                        func(ctx context.Context, forks ...engineclient.Fork) *engineclient.WatchSnapshot
+	CloseFunc                           func() error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+var _ engineclient.EngineAPI = (*MockEngineAPI)(nil)
+
+func (m *MockEngineAPI) record(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, method)
+}
+
+// Calls returns the methods called so far, oldest first
+func (m *MockEngineAPI) Calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls...)
+}
+
+func (m *MockEngineAPI) NewPayload(ctx context.Context, payload map[string]interface{}) (map[string]interface{}, error) {
+	m.record("NewPayload")
+	if m.NewPayloadFunc == nil {
+		return nil, nil
+	}
+	return m.NewPayloadFunc(ctx, payload)
+}
+
+func (m *MockEngineAPI) NewPayloadV4(ctx context.Context, payload map[string]interface{}, versionedHashes []engineclient.Hash, parentBeaconBlockRoot engineclient.Hash, executionRequests []string) (map[string]interface{}, error) {
+	m.record("NewPayloadV4")
+	if m.NewPayloadV4Func == nil {
+		return nil, nil
+	}
+	return m.NewPayloadV4Func(ctx, payload, versionedHashes, parentBeaconBlockRoot, executionRequests)
+}
+
+func (m *MockEngineAPI) NewPayloadAndWait(ctx context.Context, payload map[string]interface{}) (*engineclient.PayloadStatus, error) {
+	m.record("NewPayloadAndWait")
+	if m.NewPayloadAndWaitFunc == nil {
+		return nil, nil
+	}
+	return m.NewPayloadAndWaitFunc(ctx, payload)
+}
+
+func (m *MockEngineAPI) ForkchoiceUpdated(ctx context.Context, state engineclient.ForkChoiceState, attributes *engineclient.PayloadAttributes) (map[string]interface{}, error) {
+	m.record("ForkchoiceUpdated")
+	if m.ForkchoiceUpdatedFunc == nil {
+		return nil, nil
+	}
+	return m.ForkchoiceUpdatedFunc(ctx, state, attributes)
+}
+
+func (m *MockEngineAPI) ResumeForkchoice(ctx context.Context) (*engineclient.ForkChoiceState, map[string]interface{}, error) {
+	m.record("ResumeForkchoice")
+	if m.ResumeForkchoiceFunc == nil {
+		return nil, nil, nil
+	}
+	return m.ResumeForkchoiceFunc(ctx)
+}
+
+func (m *MockEngineAPI) RewindToLatestValid(ctx context.Context, invalid engineclient.Hash, status engineclient.PayloadStatus, current engineclient.ForkChoiceState) (*engineclient.RewindEvent, error) {
+	m.record("RewindToLatestValid")
+	if m.RewindToLatestValidFunc == nil {
+		return nil, nil
+	}
+	return m.RewindToLatestValidFunc(ctx, invalid, status, current)
+}
+
+func (m *MockEngineAPI) GetPayload(ctx context.Context, payloadID string) (map[string]interface{}, error) {
+	m.record("GetPayload")
+	if m.GetPayloadFunc == nil {
+		return nil, nil
+	}
+	return m.GetPayloadFunc(ctx, payloadID)
+}
+
+func (m *MockEngineAPI) GetPayloadV4(ctx context.Context, payloadID string) (map[string]interface{}, error) {
+	m.record("GetPayloadV4")
+	if m.GetPayloadV4Func == nil {
+		return nil, nil
+	}
+	return m.GetPayloadV4Func(ctx, payloadID)
+}
+
+func (m *MockEngineAPI) GetPayloadBodiesByHash(ctx context.Context, hashes []engineclient.Hash) (map[string]interface{}, error) {
+	m.record("GetPayloadBodiesByHash")
+	if m.GetPayloadBodiesByHashFunc == nil {
+		return nil, nil
+	}
+	return m.GetPayloadBodiesByHashFunc(ctx, hashes)
+}
+
+func (m *MockEngineAPI) GetPayloadBodiesByRange(ctx context.Context, start, count uint64) (map[string]interface{}, error) {
+	m.record("GetPayloadBodiesByRange")
+	if m.GetPayloadBodiesByRangeFunc == nil {
+		return nil, nil
+	}
+	return m.GetPayloadBodiesByRangeFunc(ctx, start, count)
+}
+
+func (m *MockEngineAPI) StreamPayloadBodiesByRange(ctx context.Context, start, count, pageSize uint64) iter.Seq2[engineclient.RangeBody, error] {
+	m.record("StreamPayloadBodiesByRange")
+	if m.StreamPayloadBodiesByRangeFunc == nil {
+		return func(func(engineclient.RangeBody, error) bool) {}
+	}
+	return m.StreamPayloadBodiesByRangeFunc(ctx, start, count, pageSize)
+}
+
+func (m *MockEngineAPI) ExchangeTransitionConfiguration(ctx context.Context, config engineclient.TransitionConfiguration) (map[string]interface{}, error) {
+	m.record("ExchangeTransitionConfiguration")
+	if m.ExchangeTransitionConfigurationFunc == nil {
+		return nil, nil
+	}
+	return m.ExchangeTransitionConfigurationFunc(ctx, config)
+}
+
+func (m *MockEngineAPI) GetClientVersion(ctx context.Context, version engineclient.ClientVersion) (map[string]interface{}, error) {
+	m.record("GetClientVersion")
+	if m.GetClientVersionFunc == nil {
+		return nil, nil
+	}
+	return m.GetClientVersionFunc(ctx, version)
+}
+
+func (m *MockEngineAPI) CheckCapabilities(ctx context.Context, forks ...engineclient.Fork) (*engineclient.CapabilityReport, error) {
+	m.record("CheckCapabilities")
+	if m.CheckCapabilitiesFunc == nil {
+		return nil, nil
+	}
+	return m.CheckCapabilitiesFunc(ctx, forks...)
+}
+
+func (m *MockEngineAPI) GetBlockByHash(ctx context.Context, hash engineclient.Hash, fullTransactions bool) (map[string]interface{}, error) {
+	m.record("GetBlockByHash")
+	if m.GetBlockByHashFunc == nil {
+		return nil, nil
+	}
+	return m.GetBlockByHashFunc(ctx, hash, fullTransactions)
+}
+
+func (m *MockEngineAPI) GetBlockByNumber(ctx context.Context, number string, fullTransactions bool) (map[string]interface{}, error) {
+	m.record("GetBlockByNumber")
+	if m.GetBlockByNumberFunc == nil {
+		return nil, nil
+	}
+	return m.GetBlockByNumberFunc(ctx, number, fullTransactions)
+}
+
+func (m *MockEngineAPI) HeadBlock(ctx context.Context) (*engineclient.HeadBlock, error) {
+	m.record("HeadBlock")
+	if m.HeadBlockFunc == nil {
+		return nil, nil
+	}
+	return m.HeadBlockFunc(ctx)
+}
+
+func (m *MockEngineAPI) SyncStatus(ctx context.Context) (*engineclient.SyncProgress, error) {
+	m.record("SyncStatus")
+	if m.SyncStatusFunc == nil {
+		return nil, nil
+	}
+	return m.SyncStatusFunc(ctx)
+}
+
+func (m *MockEngineAPI) ChainID(ctx context.Context) (uint64, error) {
+	m.record("ChainID")
+	if m.ChainIDFunc == nil {
+		return 0, nil
+	}
+	return m.ChainIDFunc(ctx)
+}
+
+func (m *MockEngineAPI) GenesisHash(ctx context.Context) (engineclient.Hash, error) {
+	m.record("GenesisHash")
+	if m.GenesisHashFunc == nil {
+		return engineclient.Hash{}, nil
+	}
+	return m.GenesisHashFunc(ctx)
+}
+
+func (m *MockEngineAPI) VerifyChain(ctx context.Context, expectedChainID uint64, expectedGenesisHash engineclient.Hash) error {
+	m.record("VerifyChain")
+	if m.VerifyChainFunc == nil {
+		return nil
+	}
+	return m.VerifyChainFunc(ctx, expectedChainID, expectedGenesisHash)
+}
+
+func (m *MockEngineAPI) GetBadBlocks(ctx context.Context) ([]engineclient.BadBlock, error) {
+	m.record("GetBadBlocks")
+	if m.GetBadBlocksFunc == nil {
+		return nil, nil
+	}
+	return m.GetBadBlocksFunc(ctx)
+}
+
+func (m *MockEngineAPI) FindBadBlock(ctx context.Context, hash engineclient.Hash) (*engineclient.BadBlock, error) {
+	m.record("FindBadBlock")
+	if m.FindBadBlockFunc == nil {
+		return nil, nil
+	}
+	return m.FindBadBlockFunc(ctx, hash)
+}
+
+func (m *MockEngineAPI) GetRawBlock(ctx context.Context, block string) ([]byte, error) {
+	m.record("GetRawBlock")
+	if m.GetRawBlockFunc == nil {
+		return nil, nil
+	}
+	return m.GetRawBlockFunc(ctx, block)
+}
+
+func (m *MockEngineAPI) GetRawHeader(ctx context.Context, block string) ([]byte, error) {
+	m.record("GetRawHeader")
+	if m.GetRawHeaderFunc == nil {
+		return nil, nil
+	}
+	return m.GetRawHeaderFunc(ctx, block)
+}
+
+func (m *MockEngineAPI) GetRawReceipts(ctx context.Context, block string) ([][]byte, error) {
+	m.record("GetRawReceipts")
+	if m.GetRawReceiptsFunc == nil {
+		return nil, nil
+	}
+	return m.GetRawReceiptsFunc(ctx, block)
+}
+
+func (m *MockEngineAPI) ImportPayloads(ctx context.Context, payloads <-chan engineclient.ImportPayload, cfg engineclient.ImportConfig) (*engineclient.ImportReport, error) {
+	m.record("ImportPayloads")
+	if m.ImportPayloadsFunc == nil {
+		return nil, nil
+	}
+	return m.ImportPayloadsFunc(ctx, payloads, cfg)
+}
+
+func (m *MockEngineAPI) FuzzPayload(ctx context.Context, item engineclient.ImportPayload, mutations []engineclient.PayloadMutation, baseline bool, onResult func(engineclient.FuzzResult)) (*engineclient.FuzzReport, error) {
+	m.record("FuzzPayload")
+	if m.FuzzPayloadFunc == nil {
+		return nil, nil
+	}
+	return m.FuzzPayloadFunc(ctx, item, mutations, baseline, onResult)
+}
+
+func (m *MockEngineAPI) FollowHeads(ctx context.Context, wsURL string, cfg engineclient.FollowerConfig) error {
+	m.record("FollowHeads")
+	if m.FollowHeadsFunc == nil {
+		return nil
+	}
+	return m.FollowHeadsFunc(ctx, wsURL, cfg)
+}
+
+func (m *MockEngineAPI) Simulate(ctx context.Context, cfg engineclient.SimulatorConfig) error {
+	m.record("Simulate")
+	if m.SimulateFunc == nil {
+		return nil
+	}
+	return m.SimulateFunc(ctx, cfg)
+}
+
+func (m *MockEngineAPI) RunScenario(ctx context.Context, s *engineclient.Scenario, keepGoing bool, onStep func(engineclient.StepResult)) *engineclient.ScenarioReport {
+	m.record("RunScenario")
+	if m.RunScenarioFunc == nil {
+		return &engineclient.ScenarioReport{}
+	}
+	return m.RunScenarioFunc(ctx, s, keepGoing, onStep)
+}
+
+func (m *MockEngineAPI) Call(ctx context.Context, method string, params interface{}) (map[string]interface{}, error) {
+	m.record("Call")
+	if m.CallFunc == nil {
+		return nil, nil
+	}
+	return m.CallFunc(ctx, method, params)
+}
+
+func (m *MockEngineAPI) CallMethod(ctx context.Context, family engineclient.MethodFamily, fork engineclient.Fork, args engineclient.MethodArgs) (map[string]interface{}, error) {
+	m.record("CallMethod")
+	if m.CallMethodFunc == nil {
+		return nil, nil
+	}
+	return m.CallMethodFunc(ctx, family, fork, args)
+}
+
+func (m *MockEngineAPI) HeadHistory() []engineclient.Hash {
+	m.record("HeadHistory")
+	if m.HeadHistoryFunc == nil {
+		return nil
+	}
+	return m.HeadHistoryFunc()
+}
+
+func (m *MockEngineAPI) Status() engineclient.StatusReport {
+	m.record("Status")
+	if m.StatusFunc == nil {
+		return engineclient.StatusReport{}
+	}
+	return m.StatusFunc()
+}
+
+func (m *MockEngineAPI) Readiness(ctx context.Context, forks ...engineclient.Fork) engineclient.ReadinessReport {
+	m.record("Readiness")
+	if m.ReadinessFunc == nil {
+		return engineclient.ReadinessReport{}
+	}
+	return m.ReadinessFunc(ctx, forks...)
+}
+
+func (m *MockEngineAPI) StatusHandler(forks ...engineclient.Fork) http.Handler {
+	m.record("StatusHandler")
+	if m.StatusHandlerFunc == nil {
+		return http.NotFoundHandler()
+	}
+	return m.StatusHandlerFunc(forks...)
+}
+
+func (m *MockEngineAPI) Snapshot(ctx context.Context, forks ...engineclient.Fork) *engineclient.WatchSnapshot {
+	m.record("Snapshot")
+	if m.SnapshotFunc == nil {
+		return &engineclient.WatchSnapshot{}
+	}
+	return m.SnapshotFunc(ctx, forks...)
+}
+
+func (m *MockEngineAPI) Close() error {
+	m.record("Close")
+	if m.CloseFunc == nil {
+		return nil
+	}
+	return m.CloseFunc()
+}
@@ -0,0 +1,45 @@
+package enginetest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	engineclient "github.com/devlongs/engine-client"
+)
+
+func TestMockEngineAPI(t *testing.T) {
+	ctx := context.Background()
+	errUnknown := errors.New("unknown payload")
+	var api engineclient.EngineAPI = &MockEngineAPI{
+		GetPayloadFunc: func(ctx context.Context, payloadID string) (map[string]interface{}, error) {
+			return nil, errUnknown
+		},
+	}
+
+	if _, err := api.GetPayload(ctx, "0x0000000000000001"); err != errUnknown {
+		t.Fatalf("GetPayload returned %v, want the Func's error", err)
+	}
+	if report := api.RunScenario(ctx, &engineclient.Scenario{}, false, nil); report == nil {
+		t.Fatal("RunScenario returned a nil report")
+	}
+	if snap := api.Snapshot(ctx); snap == nil {
+		t.Fatal("Snapshot returned a nil snapshot")
+	}
+	for range api.StreamPayloadBodiesByRange(ctx, 1, 10, 5) {
+		t.Fatal("unset StreamPayloadBodiesByRange yielded a body")
+	}
+	rec := httptest.NewRecorder()
+	api.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unset StatusHandler answered %d, want 404", rec.Code)
+	}
+
+	want := []string{"GetPayload", "RunScenario", "Snapshot", "StreamPayloadBodiesByRange", "StatusHandler"}
+	if got := api.(*MockEngineAPI).Calls(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Calls() = %v, want %v", got, want)
+	}
+}
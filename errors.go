@@ -0,0 +1,119 @@
+package engineclient
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors for common failure classes. Errors returned by the client,
+// including those from decodeResult, wrap them so callers can use errors.Is.
+var (
+	ErrUnauthorized           = errors.New("unauthorized")
+	ErrUnsupportedFork        = errors.New("unsupported fork")
+	ErrUnknownPayload         = errors.New("unknown payload")
+	ErrInvalidForkchoiceState = errors.New("invalid forkchoice state")
+	ErrTimeout                = errors.New("request timed out")
+	ErrELSyncing              = errors.New("execution client is syncing")
+)
+
+// Engine API error codes
+const (
+	codeUnknownPayload         = -38001
+	codeInvalidForkchoiceState = -38002
+	codeUnsupportedFork        = -38005
+)
+
+// RPCError is the error member of a JSON-RPC response
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+func (e *RPCError) Unwrap() error {
+	switch e.Code {
+	case codeUnknownPayload:
+		return ErrUnknownPayload
+	case codeInvalidForkchoiceState:
+		return ErrInvalidForkchoiceState
+	case codeUnsupportedFork:
+		return ErrUnsupportedFork
+	}
+	return nil
+}
+
+// HTTPStatusError reports a non-200 reply from the EL or beacon node
+type HTTPStatusError struct {
+	StatusCode int
+	// RetryAfter is the delay requested by a Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status: %d", e.StatusCode)
+}
+
+func (e *HTTPStatusError) Unwrap() error {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// wrapTimeout adds ErrTimeout to the chain of deadline and network timeout
+// errors
+func wrapTimeout(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
+// missingPayloadIDError reports a forkchoiceUpdated with attributes that did
+// not start a build, wrapping ErrELSyncing when that is the reason
+func missingPayloadIDError(status PayloadStatus) error {
+	err := fmt.Errorf("no payload id returned (status %s)", status.Status)
+	if status.Status == StatusSyncing {
+		return fmt.Errorf("%w: %w", ErrELSyncing, err)
+	}
+	return err
+}
+
+// RequestError annotates a failed call with the identifiers needed to find
+// it in EL-side logs
+type RequestError struct {
+	Method string
+	ID     uint64
+	UUID   string
+	Err    error
+}
+
+func (e *RequestError) Error() string {
+	if e.UUID != "" {
+		return fmt.Sprintf("%s [id=%d uuid=%s]: %v", e.Method, e.ID, e.UUID, e.Err)
+	}
+	return fmt.Sprintf("%s [id=%d]: %v", e.Method, e.ID, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
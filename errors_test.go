@@ -0,0 +1,125 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stubEL answers every JSON-RPC call with handle's result, or its error
+// when that is set
+func stubEL(t *testing.T, handle func(method string, params []json.RawMessage) (interface{}, *RPCError)) *httptest.Server {
+	t.Helper()
+	srv := stubELServer(handle)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// stubELServer is stubEL for callers that close the server themselves
+func stubELServer(handle func(method string, params []json.RawMessage) (interface{}, *RPCError)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     interface{}       `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, rpcErr := handle(req.Method, req.Params)
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if rpcErr != nil {
+			response["error"] = rpcErr
+		} else {
+			response["result"] = result
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestRPCErrorSentinels(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		want error
+		call func(context.Context, *EngineClient) error
+	}{
+		{"unknown payload", codeUnknownPayload, ErrUnknownPayload, func(ctx context.Context, c *EngineClient) error {
+			_, err := c.GetPayload(ctx, "0x0000000000000001")
+			return err
+		}},
+		{"invalid forkchoice state", codeInvalidForkchoiceState, ErrInvalidForkchoiceState, func(ctx context.Context, c *EngineClient) error {
+			_, err := c.ForkchoiceUpdated(ctx, ForkChoiceState{}, nil)
+			return err
+		}},
+		{"unsupported fork", codeUnsupportedFork, ErrUnsupportedFork, func(ctx context.Context, c *EngineClient) error {
+			_, err := c.Call(ctx, "engine_getPayloadV4", []interface{}{"0x0000000000000001"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := stubEL(t, func(string, []json.RawMessage) (interface{}, *RPCError) {
+				return nil, &RPCError{Code: tt.code, Message: tt.name}
+			})
+			c := NewEngineClient(srv.URL, nil, WithoutAuth())
+			err := tt.call(context.Background(), c)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want errors.Is %v", err, tt.want)
+			}
+			var rpcErr *RPCError
+			if !errors.As(err, &rpcErr) || rpcErr.Code != tt.code {
+				t.Fatalf("got %v, want *RPCError with code %d", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestUnauthorizedSentinel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	c := NewEngineClient(srv.URL, []byte("0123456789abcdef0123456789abcdef"))
+	_, err := c.ForkchoiceUpdated(context.Background(), ForkChoiceState{}, nil)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestTimeoutSentinel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	c := NewEngineClient(srv.URL, nil, WithoutAuth())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.GetPayload(ctx, "0x0000000000000001")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want ErrTimeout", err)
+	}
+}
+
+func TestSyncingSentinel(t *testing.T) {
+	srv := stubEL(t, func(string, []json.RawMessage) (interface{}, *RPCError) {
+		return PayloadStatus{Status: StatusSyncing}, nil
+	})
+	c := NewEngineClient(srv.URL, nil, WithoutAuth())
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	_, err := c.NewPayloadAndWait(ctx, map[string]interface{}{"blockHash": Hash{1}})
+	if !errors.Is(err, ErrELSyncing) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want ErrELSyncing and ErrTimeout", err)
+	}
+}
@@ -0,0 +1,179 @@
+package engineclient
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Execution request type prefixes defined by EIP-6110, EIP-7002 and EIP-7251
+const (
+	DepositRequestType       byte = 0x00
+	WithdrawalRequestType    byte = 0x01
+	ConsolidationRequestType byte = 0x02
+)
+
+const (
+	depositRequestSize       = 192
+	withdrawalRequestSize    = 76
+	consolidationRequestSize = 116
+)
+
+type DepositRequest struct {
+	Pubkey                string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawalCredentials"`
+	Amount                string `json:"amount"`
+	Signature             string `json:"signature"`
+	Index                 string `json:"index"`
+}
+
+type WithdrawalRequest struct {
+	SourceAddress   Address `json:"sourceAddress"`
+	ValidatorPubkey string  `json:"validatorPubkey"`
+	Amount          string  `json:"amount"`
+}
+
+type ConsolidationRequest struct {
+	SourceAddress Address `json:"sourceAddress"`
+	SourcePubkey  string  `json:"sourcePubkey"`
+	TargetPubkey  string  `json:"targetPubkey"`
+}
+
+// ExecutionRequests is the typed form of the executionRequests list carried by
+// engine_newPayloadV4 and engine_getPayloadV4
+type ExecutionRequests struct {
+	Deposits       []DepositRequest
+	Withdrawals    []WithdrawalRequest
+	Consolidations []ConsolidationRequest
+}
+
+// SplitExecutionRequests decodes the flattened executionRequests list, where
+// each element is a request type byte followed by the SSZ encoding of every
+// request of that type
+func SplitExecutionRequests(requests []string) (*ExecutionRequests, error) {
+	out := &ExecutionRequests{}
+	last := -1
+	for i, r := range requests {
+		b, err := decodeHex(r)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %v", i, err)
+		}
+		if len(b) < 2 {
+			return nil, fmt.Errorf("request %d: empty request data", i)
+		}
+		if int(b[0]) <= last {
+			return nil, fmt.Errorf("request %d: type 0x%02x out of order", i, b[0])
+		}
+		last = int(b[0])
+
+		data := b[1:]
+		switch b[0] {
+		case DepositRequestType:
+			err = decodeRequestList(data, depositRequestSize, func(d *sszDecoder) {
+				out.Deposits = append(out.Deposits, DepositRequest{
+					Pubkey:                d.hex(48),
+					WithdrawalCredentials: d.hex(32),
+					Amount:                d.quantity(),
+					Signature:             d.hex(96),
+					Index:                 d.quantity(),
+				})
+			})
+		case WithdrawalRequestType:
+			err = decodeRequestList(data, withdrawalRequestSize, func(d *sszDecoder) {
+				var w WithdrawalRequest
+				d.read(w.SourceAddress[:])
+				w.ValidatorPubkey = d.hex(48)
+				w.Amount = d.quantity()
+				out.Withdrawals = append(out.Withdrawals, w)
+			})
+		case ConsolidationRequestType:
+			err = decodeRequestList(data, consolidationRequestSize, func(d *sszDecoder) {
+				var c ConsolidationRequest
+				d.read(c.SourceAddress[:])
+				c.SourcePubkey = d.hex(48)
+				c.TargetPubkey = d.hex(48)
+				out.Consolidations = append(out.Consolidations, c)
+			})
+		default:
+			err = fmt.Errorf("unknown request type 0x%02x", b[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %v", i, err)
+		}
+	}
+	return out, nil
+}
+
+func decodeRequestList(data []byte, size int, decode func(*sszDecoder)) error {
+	if len(data)%size != 0 {
+		return fmt.Errorf("data length %d is not a multiple of %d", len(data), size)
+	}
+	d := &sszDecoder{buf: data}
+	for d.pos < len(data) {
+		decode(d)
+	}
+	return nil
+}
+
+// Join flattens the requests into the executionRequests list, omitting types
+// that have no requests
+func (r *ExecutionRequests) Join() ([]string, error) {
+	requests := []string{}
+
+	if len(r.Deposits) > 0 {
+		e := &sszEncoder{buf: []byte{DepositRequestType}}
+		for _, d := range r.Deposits {
+			e.fixed("pubkey", d.Pubkey, 48)
+			e.fixed("withdrawalCredentials", d.WithdrawalCredentials, 32)
+			e.uint64("amount", d.Amount)
+			e.fixed("signature", d.Signature, 96)
+			e.uint64("index", d.Index)
+		}
+		if e.err != nil {
+			return nil, fmt.Errorf("deposit request: %v", e.err)
+		}
+		requests = append(requests, encodeHex(e.buf))
+	}
+	if len(r.Withdrawals) > 0 {
+		e := &sszEncoder{buf: []byte{WithdrawalRequestType}}
+		for _, w := range r.Withdrawals {
+			e.raw(w.SourceAddress[:])
+			e.fixed("validatorPubkey", w.ValidatorPubkey, 48)
+			e.uint64("amount", w.Amount)
+		}
+		if e.err != nil {
+			return nil, fmt.Errorf("withdrawal request: %v", e.err)
+		}
+		requests = append(requests, encodeHex(e.buf))
+	}
+	if len(r.Consolidations) > 0 {
+		e := &sszEncoder{buf: []byte{ConsolidationRequestType}}
+		for _, c := range r.Consolidations {
+			e.raw(c.SourceAddress[:])
+			e.fixed("sourcePubkey", c.SourcePubkey, 48)
+			e.fixed("targetPubkey", c.TargetPubkey, 48)
+		}
+		if e.err != nil {
+			return nil, fmt.Errorf("consolidation request: %v", e.err)
+		}
+		requests = append(requests, encodeHex(e.buf))
+	}
+	return requests, nil
+}
+
+// RequestsHash computes the EIP-7685 requestsHash header field committing to
+// the flattened executionRequests list
+func RequestsHash(requests []string) (Hash, error) {
+	outer := sha256.New()
+	for i, r := range requests {
+		b, err := decodeHex(r)
+		if err != nil {
+			return Hash{}, fmt.Errorf("request %d: %v", i, err)
+		}
+		if len(b) < 2 {
+			continue
+		}
+		inner := sha256.Sum256(b)
+		outer.Write(inner[:])
+	}
+	return Hash(outer.Sum(nil)), nil
+}
@@ -0,0 +1,224 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// maxBodiesPerRequest is the largest count getPayloadBodiesByRangeV1 must
+// accept
+const maxBodiesPerRequest = 1024
+
+// GetPayloadBodiesByRange fetches the bodies of count consecutive blocks
+// starting at start. The EL truncates the list at its head.
+func (c *EngineClient) GetPayloadBodiesByRange(ctx context.Context, start, count uint64) (map[string]interface{}, error) {
+	params := []interface{}{fmt.Sprintf("0x%x", start), fmt.Sprintf("0x%x", count)}
+	return c.makeRequest(ctx, "engine_getPayloadBodiesByRangeV1", params)
+}
+
+// exportProgress is saved beside the output after every page so an
+// interrupted export can continue where it stopped
+type exportProgress struct {
+	Next   uint64 `json:"next"`
+	Offset int64  `json:"offset"`
+	Format string `json:"format"`
+}
+
+func loadExportProgress(path string) (*exportProgress, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read progress: %v", err)
+	}
+	var p exportProgress
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid progress file %s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// encodeBody appends one exported body in the given format
+func encodeBody(buf []byte, format string, number uint64, body *PayloadBody) ([]byte, error) {
+	if format == "jsonl" {
+		line, err := json.Marshal(struct {
+			Number string `json:"number"`
+			*PayloadBody
+		}{fmt.Sprintf("0x%x", number), body})
+		if err != nil {
+			return nil, err
+		}
+		return append(append(buf, line...), '\n'), nil
+	}
+
+	// CBOR records form an RFC 8742 sequence of maps with raw byte strings
+	// in place of hex
+	e := &cborEncoder{buf: buf}
+	e.mapHeader(3)
+	e.text("number")
+	e.uint(number)
+	e.text("transactions")
+	e.array(len(body.Transactions))
+	for i, tx := range body.Transactions {
+		raw, err := decodeHex(tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		e.bytes(raw)
+	}
+	e.text("withdrawals")
+	if body.Withdrawals == nil {
+		e.null()
+		return e.buf, nil
+	}
+	e.array(len(body.Withdrawals))
+	for i, w := range body.Withdrawals {
+		e.mapHeader(4)
+		for _, f := range []struct{ name, value string }{
+			{"index", w.Index},
+			{"validatorIndex", w.ValidatorIndex},
+			{"amount", w.Amount},
+		} {
+			v, err := decodeQuantity(f.value)
+			if err != nil {
+				return nil, fmt.Errorf("withdrawal %d %s: %v", i, f.name, err)
+			}
+			e.text(f.name)
+			e.uint(v)
+		}
+		e.text("address")
+		e.bytes(w.Address[:])
+	}
+	return e.buf, nil
+}
+
+// runExport pages through getPayloadBodiesByRange and writes the bodies to
+// a file, recording progress after each page so a rerun resumes
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	from := fs.Uint64("from", 0, "first block to export")
+	to := fs.Uint64("to", 0, "last block to export (inclusive)")
+	outPath := fs.String("out", "bodies.jsonl", "output file")
+	format := fs.String("format", "jsonl", "output format: jsonl or cbor")
+	page := fs.Uint64("page", 128, "bodies to request per call")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout for each call")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	if *to < *from {
+		return fmt.Errorf("-to must not be below -from")
+	}
+	if *format != "jsonl" && *format != "cbor" {
+		return fmt.Errorf("unknown format %q (want jsonl or cbor)", *format)
+	}
+	if *page == 0 || *page > maxBodiesPerRequest {
+		return fmt.Errorf("-page must be between 1 and %d", maxBodiesPerRequest)
+	}
+
+	progressPath := *outPath + ".progress"
+	progress, err := loadExportProgress(progressPath)
+	if err != nil {
+		return err
+	}
+	next, offset := *from, int64(0)
+	if progress != nil {
+		if progress.Format != *format {
+			return fmt.Errorf("%s was started as %s; rerun with -format %s or remove %s", *outPath, progress.Format, progress.Format, progressPath)
+		}
+		next, offset = progress.Next, progress.Offset
+		out.note("resuming at block %d", next)
+	}
+
+	f, err := os.OpenFile(*outPath, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", *outPath, err)
+	}
+	defer f.Close()
+	// Drop anything written after the last recorded page.
+	if err := f.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate %s: %v", *outPath, err)
+	}
+	if _, err := f.Seek(offset, 0); err != nil {
+		return fmt.Errorf("failed to seek %s: %v", *outPath, err)
+	}
+
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	start := time.Now()
+	exported := 0
+	for next <= *to {
+		count := min(*page, *to-next+1)
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		response, err := client.GetPayloadBodiesByRange(ctx, next, count)
+		cancel()
+		if err != nil {
+			return err
+		}
+		var bodies []*PayloadBody
+		if err := decodeResult(response, &bodies); err != nil {
+			return err
+		}
+		if len(bodies) == 0 {
+			return fmt.Errorf("no bodies returned from block %d; is the EL synced that far?", next)
+		}
+
+		var buf []byte
+		var unavailable bool
+		written := uint64(0)
+		for _, body := range bodies {
+			if body == nil {
+				unavailable = true
+				break
+			}
+			if buf, err = encodeBody(buf, *format, next+written, body); err != nil {
+				return fmt.Errorf("block %d: %v", next+written, err)
+			}
+			written++
+		}
+		if _, err := f.Write(buf); err != nil {
+			return fmt.Errorf("failed to write %s: %v", *outPath, err)
+		}
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("failed to sync %s: %v", *outPath, err)
+		}
+		offset += int64(len(buf))
+		next += written
+		exported += int(written)
+		state, _ := json.Marshal(exportProgress{Next: next, Offset: offset, Format: *format})
+		if err := writeFileAtomic(progressPath, state); err != nil {
+			return fmt.Errorf("failed to save progress: %v", err)
+		}
+		if unavailable {
+			return fmt.Errorf("body of block %d is unavailable; rerun to resume once the EL has it", next)
+		}
+	}
+
+	os.Remove(progressPath)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	rec := record{{"from", *from}, {"to", *to}, {"exported", exported}, {"file", *outPath}, {"elapsed", elapsed}}
+	out.emit(rec, func(w io.Writer) {
+		fmt.Fprintf(w, "exported %d bodies to %s in %s\n", exported, *outPath, elapsed)
+	})
+	return nil
+}
@@ -0,0 +1,199 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// FollowerConfig controls how FollowHeads derives safe and finalized blocks
+type FollowerConfig struct {
+	// SafeLag and FinalizedLag are how many blocks behind the head the safe
+	// and finalized hashes trail. Until enough heads have been seen they stay
+	// at the zero hash.
+	SafeLag      uint64
+	FinalizedLag uint64
+	// OnUpdate, if set, is called after every forkchoiceUpdated
+	OnUpdate func(state ForkChoiceState, number uint64, response map[string]interface{}, err error)
+}
+
+type newHeadNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		Subscription string `json:"subscription"`
+		Result       struct {
+			Hash   Hash   `json:"hash"`
+			Number string `json:"number"`
+		} `json:"result"`
+	} `json:"params"`
+}
+
+// FollowHeads subscribes to newHeads over an authenticated websocket and
+// issues forkchoiceUpdated for every new head, acting as a minimal head-sync
+// driver for test networks. It runs until ctx is done or the subscription
+// fails.
+func (c *EngineClient) FollowHeads(ctx context.Context, wsURL string, cfg FollowerConfig) error {
+	header := http.Header{}
+	token, err := c.authToken()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+		c.auditToken(token, requestInfo{method: "eth_subscribe"})
+	}
+	c.applyHeaders(ctx, header)
+	conn, err := dialWebSocket(ctx, wsURL, header, c.proxyFor, c.dialer.DialContext)
+	if err != nil {
+		return err
+	}
+	var closeOnce sync.Once
+	closeConn := func() { closeOnce.Do(func() { conn.Close() }) }
+	defer closeConn()
+	stop := context.AfterFunc(ctx, closeConn)
+	defer stop()
+
+	subscribe, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_subscribe",
+		"params":  []interface{}{"newHeads"},
+	})
+	if err := conn.WriteText(subscribe); err != nil {
+		return err
+	}
+	msg, err := conn.ReadMessage()
+	if err != nil {
+		return fmt.Errorf("failed to read subscription response: %v", err)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(msg, &response); err != nil {
+		return fmt.Errorf("failed to decode subscription response: %v", err)
+	}
+	var subscription string
+	if err := decodeResult(response, &subscription); err != nil {
+		return fmt.Errorf("eth_subscribe failed: %w", err)
+	}
+
+	canonical := make(map[uint64]Hash)
+	var state ForkChoiceState
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("newHeads subscription ended: %v", err)
+		}
+		var n newHeadNotification
+		if err := json.Unmarshal(msg, &n); err != nil || n.Method != "eth_subscription" || n.Params.Subscription != subscription {
+			continue
+		}
+		number, err := decodeQuantity(n.Params.Result.Number)
+		if err != nil {
+			continue
+		}
+
+		// A head at or below a number already seen replaces that branch.
+		for seen := range canonical {
+			if seen >= number || seen+cfg.SafeLag+cfg.FinalizedLag+1 < number {
+				delete(canonical, seen)
+			}
+		}
+		canonical[number] = n.Params.Result.Hash
+
+		state.HeadBlockHash = n.Params.Result.Hash
+		if hash, ok := lagged(canonical, number, cfg.SafeLag); ok {
+			state.SafeBlockHash = hash
+		}
+		if hash, ok := lagged(canonical, number, cfg.FinalizedLag); ok {
+			state.FinalizedBlockHash = hash
+		}
+		result, err := c.ForkchoiceUpdated(ctx, state, nil)
+		if cfg.OnUpdate != nil {
+			cfg.OnUpdate(state, number, result, err)
+		}
+	}
+}
+
+func lagged(canonical map[uint64]Hash, head, lag uint64) (Hash, bool) {
+	if lag > head {
+		return Hash{}, false
+	}
+	hash, ok := canonical[head-lag]
+	return hash, ok
+}
+
+// runFollow drives forkchoiceUpdated from the EL's own newHeads stream
+func runFollow(args []string) error {
+	fs := flag.NewFlagSet("follow", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	wsURL := fs.String("ws", "", "authenticated websocket endpoint (defaults to -endpoint with a ws scheme)")
+	safeLag := fs.Uint64("safe-lag", 32, "blocks between head and safe")
+	finalizedLag := fs.Uint64("finalized-lag", 64, "blocks between head and finalized")
+	statusAddr := fs.String("status-addr", "", "serve /healthz, /readyz and /status on this address")
+	rewind := fs.Bool("rewind", false, "send the head back to the latest valid block when the EL rejects one")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	if *wsURL == "" {
+		*wsURL = "ws" + strings.TrimPrefix(cfg.Endpoint, "http")
+	}
+	var opts []Option
+	if *rewind {
+		opts = append(opts, WithAutoRewind(func(e RewindEvent, err error) {
+			if err != nil {
+				out.note("rewind to %s after invalid %s failed: %v", e.LatestValidHash, e.InvalidBlock, err)
+				return
+			}
+			out.note("rewound to %s after invalid %s (%d blocks invalidated)", e.LatestValidHash, e.InvalidBlock, len(e.Invalidated))
+		}))
+	}
+	client, err := cfg.newClient(opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	startStatusServer(*statusAddr, client, client.fork)
+
+	return client.FollowHeads(context.Background(), *wsURL, FollowerConfig{
+		SafeLag:      *safeLag,
+		FinalizedLag: *finalizedLag,
+		OnUpdate: func(state ForkChoiceState, number uint64, response map[string]interface{}, err error) {
+			var result ForkchoiceUpdatedResult
+			if err == nil {
+				err = decodeResult(response, &result)
+			}
+			rec := record{
+				{"block", number},
+				{"head", state.HeadBlockHash},
+				{"status", result.PayloadStatus.Status},
+				{"safe", state.SafeBlockHash},
+				{"finalized", state.FinalizedBlockHash},
+				{"error", err},
+			}
+			out.emit(rec, func(w io.Writer) {
+				if err != nil {
+					fmt.Fprintf(w, "block %d %s: %v\n", number, state.HeadBlockHash, err)
+					return
+				}
+				fmt.Fprintf(w, "block %d %s: %s (safe %s, finalized %s)\n", number, state.HeadBlockHash,
+					result.PayloadStatus.Status, state.SafeBlockHash, state.FinalizedBlockHash)
+			})
+		},
+	})
+}
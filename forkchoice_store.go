@@ -0,0 +1,110 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ForkchoiceStore persists the last forkchoice state the EL accepted
+type ForkchoiceStore interface {
+	Save(state ForkChoiceState) error
+	// Load returns nil when no state has been saved yet
+	Load() (*ForkChoiceState, error)
+}
+
+// MemoryForkchoiceStore keeps the state in memory only
+type MemoryForkchoiceStore struct {
+	mu    sync.Mutex
+	state *ForkChoiceState
+}
+
+func (s *MemoryForkchoiceStore) Save(state ForkChoiceState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = &state
+	return nil
+}
+
+func (s *MemoryForkchoiceStore) Load() (*ForkChoiceState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		return nil, nil
+	}
+	state := *s.state
+	return &state, nil
+}
+
+// FileForkchoiceStore keeps the state as JSON in a single file, replacing it
+// atomically on every save
+type FileForkchoiceStore struct {
+	path string
+}
+
+func NewFileForkchoiceStore(path string) *FileForkchoiceStore {
+	return &FileForkchoiceStore{path: path}
+}
+
+func (s *FileForkchoiceStore) Save(state ForkChoiceState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal forkchoice state: %v", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to save forkchoice state: %v", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data via a temp file and rename, so
+// readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FileForkchoiceStore) Load() (*ForkChoiceState, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read forkchoice state: %v", err)
+	}
+	var state ForkChoiceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode forkchoice state: %v", err)
+	}
+	return &state, nil
+}
+
+// ResumeForkchoice re-issues the persisted forkchoice state, without payload
+// attributes, so a restarted process picks up where it left off. It returns a
+// nil state when nothing has been persisted.
+func (c *EngineClient) ResumeForkchoice(ctx context.Context) (*ForkChoiceState, map[string]interface{}, error) {
+	if c.forkchoiceStore == nil {
+		return nil, nil, fmt.Errorf("no forkchoice store configured")
+	}
+	state, err := c.forkchoiceStore.Load()
+	if err != nil || state == nil {
+		return nil, nil, err
+	}
+	response, err := c.ForkchoiceUpdated(ctx, *state, nil)
+	return state, response, err
+}
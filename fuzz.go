@@ -0,0 +1,398 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// errNotApplicable marks a mutation the payload offers nothing to corrupt
+// for, such as a transaction mutation on an empty block
+var errNotApplicable = errors.New("not applicable to this payload")
+
+// PayloadMutation corrupts one aspect of a valid payload
+type PayloadMutation struct {
+	Name string
+	// Rehash recomputes the block hash after mutating, so the EL has to
+	// validate the block's contents rather than stop at the hash check
+	Rehash bool
+	Mutate func(p *ExecutionPayload) error
+}
+
+// Fuzz verdicts. A corrupt payload must be rejected; accepted means the EL
+// returned VALID for it, and unreachable that it stopped answering.
+const (
+	VerdictRejected     = "rejected"
+	VerdictAccepted     = "accepted"
+	VerdictInconclusive = "inconclusive"
+	VerdictError        = "error"
+	VerdictUnreachable  = "unreachable"
+	VerdictSkipped      = "skipped"
+)
+
+// FuzzResult is the EL's response to one mutated payload
+type FuzzResult struct {
+	Mutation string
+	Method   string
+	Status   string
+	Verdict  string
+	Detail   string
+	Duration time.Duration
+}
+
+// FuzzReport summarizes a fuzzing run
+type FuzzReport struct {
+	// Baseline is the status of the unmutated payload, empty if not checked
+	Baseline string
+	Results  []FuzzResult
+	// Accepted counts corrupt payloads the EL judged VALID
+	Accepted int
+	// Unreachable is set when the EL stopped answering, which ends the run
+	Unreachable bool
+}
+
+// PayloadMutations are the corruptions FuzzPayload applies by default
+var PayloadMutations = []PayloadMutation{
+	{"wrong block hash", false, func(p *ExecutionPayload) error {
+		p.BlockHash[31] ^= 0xff
+		return nil
+	}},
+	{"bad state root", true, func(p *ExecutionPayload) error {
+		p.StateRoot[31] ^= 0xff
+		return nil
+	}},
+	{"bad receipts root", true, func(p *ExecutionPayload) error {
+		p.ReceiptsRoot[31] ^= 0xff
+		return nil
+	}},
+	{"bad logs bloom", true, func(p *ExecutionPayload) error {
+		bloom, err := decodeFixedHex(p.LogsBloom, 256)
+		if err != nil {
+			return err
+		}
+		bloom[0] ^= 0xff
+		p.LogsBloom = encodeHex(bloom)
+		return nil
+	}},
+	{"oversized extraData", true, func(p *ExecutionPayload) error {
+		p.ExtraData = encodeHex([]byte(strings.Repeat("\xff", 33)))
+		return nil
+	}},
+	{"gasUsed above gasLimit", true, func(p *ExecutionPayload) error {
+		limit, err := decodeQuantity(p.GasLimit)
+		p.GasUsed = encodeQuantity(limit + 1)
+		return err
+	}},
+	{"gasLimit doubled", true, func(p *ExecutionPayload) error {
+		return addQuantity(&p.GasLimit, func(v uint64) uint64 { return 2*v + 1 })
+	}},
+	{"wrong baseFeePerGas", true, func(p *ExecutionPayload) error {
+		fee, err := decodeBigQuantity(p.BaseFeePerGas)
+		if err != nil {
+			return err
+		}
+		p.BaseFeePerGas = "0x" + fee.Add(fee, big.NewInt(1)).Text(16)
+		return nil
+	}},
+	{"zero timestamp", true, func(p *ExecutionPayload) error {
+		p.Timestamp = "0x0"
+		return nil
+	}},
+	{"wrong block number", true, func(p *ExecutionPayload) error {
+		return addQuantity(&p.BlockNumber, func(v uint64) uint64 { return v + 1 })
+	}},
+	{"invalid transaction RLP", true, func(p *ExecutionPayload) error {
+		// A list header promising more bytes than follow
+		p.Transactions = append(p.Transactions, "0xf8ff01")
+		return nil
+	}},
+	{"empty transaction", true, func(p *ExecutionPayload) error {
+		p.Transactions = append(p.Transactions, "0x")
+		return nil
+	}},
+	{"truncated transaction", true, func(p *ExecutionPayload) error {
+		if len(p.Transactions) == 0 || len(p.Transactions[0]) < 6 {
+			return errNotApplicable
+		}
+		p.Transactions[0] = p.Transactions[0][:len(p.Transactions[0])-2]
+		return nil
+	}},
+	{"duplicated transaction", true, func(p *ExecutionPayload) error {
+		if len(p.Transactions) == 0 {
+			return errNotApplicable
+		}
+		p.Transactions = append(p.Transactions, p.Transactions[0])
+		return nil
+	}},
+	{"altered withdrawal amount", true, func(p *ExecutionPayload) error {
+		if len(p.Withdrawals) == 0 {
+			return errNotApplicable
+		}
+		return addQuantity(&p.Withdrawals[0].Amount, func(v uint64) uint64 { return v + 1 })
+	}},
+	{"wrong blobGasUsed", true, func(p *ExecutionPayload) error {
+		if p.BlobGasUsed == nil {
+			return errNotApplicable
+		}
+		return addQuantity(p.BlobGasUsed, func(v uint64) uint64 { return v + 1<<17 })
+	}},
+}
+
+// addQuantity replaces a hex quantity with f of its value
+func addQuantity(q *string, f func(uint64) uint64) error {
+	v, err := decodeQuantity(*q)
+	if err != nil {
+		return err
+	}
+	*q = encodeQuantity(f(v))
+	return nil
+}
+
+func encodeQuantity(v uint64) string {
+	return fmt.Sprintf("0x%x", v)
+}
+
+// FuzzPayload submits one corrupted copy of item per mutation and checks
+// that the EL rejects each. With baseline set the unmutated payload is sent
+// first, so a parent the EL does not know, which makes every verdict
+// inconclusive, is noticed up front. Fuzzing stops early if the EL stops
+// answering.
+func (c *EngineClient) FuzzPayload(ctx context.Context, item ImportPayload, mutations []PayloadMutation, baseline bool, onResult func(FuzzResult)) (*FuzzReport, error) {
+	original, err := DecodeExecutionPayload(item.Payload)
+	if err != nil {
+		return nil, err
+	}
+	var requestsHash *Hash
+	if item.ExecutionRequests != nil {
+		h, err := RequestsHash(item.ExecutionRequests)
+		if err != nil {
+			return nil, err
+		}
+		requestsHash = &h
+	}
+	fork := item.fork()
+	report := &FuzzReport{}
+
+	if baseline {
+		result := c.submitFuzzed(ctx, fork, item, "baseline")
+		if result.Verdict == VerdictError || result.Verdict == VerdictUnreachable {
+			return report, fmt.Errorf("baseline payload: %s", result.Detail)
+		}
+		report.Baseline = result.Status
+	}
+
+	for _, m := range mutations {
+		result := FuzzResult{Mutation: m.Name}
+		p := *original
+		p.Transactions = append([]string(nil), original.Transactions...)
+		p.Withdrawals = append([]Withdrawal(nil), original.Withdrawals...)
+		if original.BlobGasUsed != nil {
+			v := *original.BlobGasUsed
+			p.BlobGasUsed = &v
+		}
+
+		err := m.Mutate(&p)
+		if err == nil && m.Rehash {
+			p.BlockHash, err = ComputeBlockHash(&p, item.ParentBeaconBlockRoot, requestsHash)
+		}
+		var mutated map[string]interface{}
+		if err == nil {
+			mutated, err = overlayPayload(item.Payload, &p)
+		}
+		switch {
+		case errors.Is(err, errNotApplicable):
+			result.Verdict, result.Detail = VerdictSkipped, err.Error()
+		case err != nil:
+			result.Verdict, result.Detail = VerdictError, fmt.Sprintf("failed to mutate: %v", err)
+		default:
+			fuzzed := item
+			fuzzed.Payload = mutated
+			result = c.submitFuzzed(ctx, fork, fuzzed, m.Name)
+		}
+
+		report.Results = append(report.Results, result)
+		if result.Verdict == VerdictAccepted {
+			report.Accepted++
+		}
+		if onResult != nil {
+			onResult(result)
+		}
+		if result.Verdict == VerdictUnreachable {
+			report.Unreachable = true
+			break
+		}
+	}
+	return report, nil
+}
+
+// submitFuzzed sends a payload with newPayload and classifies the response
+func (c *EngineClient) submitFuzzed(ctx context.Context, fork Fork, item ImportPayload, name string) FuzzResult {
+	result := FuzzResult{Mutation: name}
+	method, params, err := c.methods.Encode(FamilyNewPayload, fork, item.methodArgs())
+	if err != nil {
+		result.Verdict, result.Detail = VerdictError, err.Error()
+		return result
+	}
+	result.Method = method
+
+	start := c.clock.Now()
+	response, err := c.makeRequest(ctx, method, params)
+	result.Duration = c.clock.Now().Sub(start)
+	var status PayloadStatus
+	if err == nil {
+		err = decodeResult(response, &status)
+	}
+	var rpcErr *RPCError
+	switch {
+	case errors.As(err, &rpcErr):
+		// Refusing the params outright is as good a rejection as INVALID.
+		result.Verdict, result.Detail = VerdictRejected, rpcErr.Error()
+	case err != nil:
+		result.Verdict, result.Detail = VerdictError, err.Error()
+		if c.ping(ctx) != nil {
+			result.Verdict = VerdictUnreachable
+			result.Detail = fmt.Sprintf("EL stopped answering: %v", err)
+		}
+	default:
+		result.Status = status.Status
+		if status.ValidationError != nil {
+			result.Detail = *status.ValidationError
+		}
+		switch status.Status {
+		case StatusInvalid, StatusInvalidBlockHash:
+			result.Verdict = VerdictRejected
+		case StatusValid:
+			result.Verdict = VerdictAccepted
+		default:
+			result.Verdict = VerdictInconclusive
+		}
+	}
+	return result
+}
+
+// ping checks the EL still answers at all
+func (c *EngineClient) ping(ctx context.Context) error {
+	_, err := c.makeRequest(ctx, "engine_exchangeCapabilities", []interface{}{[]string{}})
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return nil
+	}
+	return err
+}
+
+// overlayPayload writes the fields of p over a copy of the original payload,
+// keeping any fields ExecutionPayload does not model and adding none the
+// original lacked
+func overlayPayload(original map[string]interface{}, p *ExecutionPayload) (map[string]interface{}, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(original))
+	for k, v := range original {
+		out[k] = v
+		if replaced, ok := fields[k]; ok {
+			out[k] = replaced
+		}
+	}
+	return out, nil
+}
+
+// runFuzz corrupts a valid payload in every known way and fails if the EL
+// accepts any of the results
+func runFuzz(args []string) error {
+	fs := flag.NewFlagSet("fuzz", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	file := fs.String("file", "", "valid payload, in any form import accepts")
+	only := fs.String("mutations", "", "comma-separated mutation names to run (default all)")
+	baseline := fs.Bool("baseline", true, "submit the unmutated payload first")
+	timeout := fs.Duration("timeout", 5*time.Minute, "timeout for the whole run")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	defer out.batch()()
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", *file, err)
+	}
+	item, err := parseChainLine(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("%s: %v", *file, err)
+	}
+	mutations := PayloadMutations
+	if *only != "" {
+		mutations = nil
+		for _, name := range strings.Split(*only, ",") {
+			i := -1
+			for j, m := range PayloadMutations {
+				if m.Name == strings.TrimSpace(name) {
+					i = j
+				}
+			}
+			if i < 0 {
+				return fmt.Errorf("unknown mutation %q", name)
+			}
+			mutations = append(mutations, PayloadMutations[i])
+		}
+	}
+
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	report, err := client.FuzzPayload(ctx, item, mutations, *baseline, func(r FuzzResult) {
+		rec := record{
+			{"mutation", r.Mutation}, {"method", r.Method}, {"status", r.Status},
+			{"verdict", r.Verdict}, {"duration", r.Duration.Round(time.Millisecond)}, {"detail", r.Detail},
+		}
+		out.emit(rec, func(w io.Writer) {
+			line := fmt.Sprintf("%-12s %s", r.Verdict, r.Mutation)
+			if r.Status != "" {
+				line += ": " + r.Status
+			}
+			if r.Detail != "" {
+				line += " (" + r.Detail + ")"
+			}
+			fmt.Fprintln(w, line)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if report.Baseline != "" && report.Baseline != StatusValid {
+		out.note("baseline payload was %s; verdicts other than accepted may not reflect validation", report.Baseline)
+	}
+	switch {
+	case report.Unreachable:
+		return fmt.Errorf("EL stopped answering during fuzzing")
+	case report.Accepted > 0:
+		return fmt.Errorf("EL accepted %d corrupt payloads as VALID", report.Accepted)
+	}
+	return nil
+}
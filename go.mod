@@ -3,3 +3,8 @@ module github.com/devlongs/engine-client
 go 1.23.3
 
 require github.com/golang-jwt/jwt/v4 v4.5.1
+
+require (
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sys v0.28.0 // indirect
+)
@@ -0,0 +1,36 @@
+package engineclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type requestHeadersKey struct{}
+
+// WithRequestHeaders returns a context that makes calls made with it send
+// the given headers in addition to the client's own, overriding any the
+// client sets with the same name
+func WithRequestHeaders(ctx context.Context, header http.Header) context.Context {
+	merged := http.Header{}
+	if prev, ok := ctx.Value(requestHeadersKey{}).(http.Header); ok {
+		for k, v := range prev {
+			merged[k] = v
+		}
+	}
+	for k, v := range header {
+		merged[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	return context.WithValue(ctx, requestHeadersKey{}, merged)
+}
+
+// applyHeaders sets the client's extra headers and then any attached to ctx
+func (c *EngineClient) applyHeaders(ctx context.Context, h http.Header) {
+	for k, v := range c.headers {
+		h[k] = append([]string(nil), v...)
+	}
+	if extra, ok := ctx.Value(requestHeadersKey{}).(http.Header); ok {
+		for k, v := range extra {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+}
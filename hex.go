@@ -0,0 +1,62 @@
+package engineclient
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decodeHex decodes a 0x-prefixed hex string into bytes
+func decodeHex(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return nil, fmt.Errorf("hex string without 0x prefix: %q", s)
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex string %q: %v", s, err)
+	}
+	return b, nil
+}
+
+// decodeFixedHex decodes a 0x-prefixed hex string of exactly n bytes
+func decodeFixedHex(s string, n int) ([]byte, error) {
+	b, err := decodeHex(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != n {
+		return nil, fmt.Errorf("hex string %q has length %d, want %d", s, len(b), n)
+	}
+	return b, nil
+}
+
+// decodeQuantity decodes a 0x-prefixed hex quantity into a uint64
+func decodeQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("quantity without 0x prefix: %q", s)
+	}
+	v, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %v", s, err)
+	}
+	return v, nil
+}
+
+// decodeBigQuantity decodes a 0x-prefixed hex quantity into a big.Int
+func decodeBigQuantity(s string) (*big.Int, error) {
+	if !strings.HasPrefix(s, "0x") || len(s) == 2 {
+		return nil, fmt.Errorf("invalid quantity: %q", s)
+	}
+	v, ok := new(big.Int).SetString(s[2:], 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid quantity: %q", s)
+	}
+	return v, nil
+}
+
+// encodeHex encodes bytes as a 0x-prefixed hex string
+func encodeHex(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
@@ -0,0 +1,58 @@
+package engineclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IDPolicy controls which response ids are accepted as matching a request.
+// Requests always carry a numeric id.
+type IDPolicy int
+
+const (
+	// IDNumberOrString accepts the request's numeric id or the same value
+	// echoed as a decimal or 0x-prefixed string. It is the default.
+	IDNumberOrString IDPolicy = iota
+	// IDStrict accepts only the numeric id that was sent
+	IDStrict
+	// IDAllowNull additionally accepts responses with a null or missing id,
+	// for middleboxes that drop it
+	IDAllowNull
+)
+
+// checkResponseID verifies that a response answers the request with id.
+// Error responses with a null id are always accepted, since JSON-RPC uses
+// them when the server could not read the request id.
+func checkResponseID(policy IDPolicy, id uint64, response map[string]interface{}) error {
+	got, present := response["id"]
+	switch v := got.(type) {
+	case nil:
+		if policy == IDAllowNull || (present && response["error"] != nil) {
+			return nil
+		}
+		if !present {
+			return fmt.Errorf("response has no id, want %d", id)
+		}
+	case float64:
+		if v == float64(id) {
+			return nil
+		}
+	case string:
+		if policy != IDStrict && stringIDMatches(v, id) {
+			return nil
+		}
+	}
+	return fmt.Errorf("response id %v does not match request id %d", got, id)
+}
+
+func stringIDMatches(s string, id uint64) bool {
+	var v uint64
+	var err error
+	if hex, ok := strings.CutPrefix(s, "0x"); ok {
+		v, err = strconv.ParseUint(hex, 16, 64)
+	} else {
+		v, err = strconv.ParseUint(s, 10, 64)
+	}
+	return err == nil && v == id
+}
@@ -0,0 +1,248 @@
+package engineclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ImportPayload is one block to import: the payload plus the newPayload
+// parameters later forks add alongside it
+type ImportPayload struct {
+	Payload               map[string]interface{}
+	VersionedHashes       []Hash
+	ParentBeaconBlockRoot *Hash
+	ExecutionRequests     []string
+}
+
+// ImportResult is the outcome of submitting one payload
+type ImportResult struct {
+	Index     int
+	Number    uint64
+	BlockHash Hash
+	Status    *PayloadStatus
+	Duration  time.Duration
+	Err       error
+}
+
+// ImportConfig controls ImportPayloads
+type ImportConfig struct {
+	// Workers bounds the number of payloads decoded, verified and submitted
+	// at once
+	Workers int
+	// OnResult, if set, is called for every payload as soon as its
+	// newPayload returns, from the worker goroutine
+	OnResult func(ImportResult)
+	// SkipForkchoice leaves the final forkchoiceUpdated to the caller, for
+	// streams that may end in a read error after which the last imported
+	// block must not become the head
+	SkipForkchoice bool
+}
+
+// ImportReport summarises an import
+type ImportReport struct {
+	Submitted  int
+	Failed     int
+	Statuses   map[string]int
+	Head       Hash
+	Forkchoice map[string]interface{}
+	Elapsed    time.Duration
+}
+
+// fork infers the newPayload version whose parameters the item carries
+func (item *ImportPayload) fork() Fork {
+	switch {
+	case item.ExecutionRequests != nil:
+		return ForkPrague
+	case item.ParentBeaconBlockRoot != nil:
+		return ForkCancun
+	case item.Payload["withdrawals"] != nil:
+		return ForkShanghai
+	default:
+		return ForkParis
+	}
+}
+
+func (item *ImportPayload) methodArgs() MethodArgs {
+	return MethodArgs{
+		Payload:               item.Payload,
+		VersionedHashes:       item.VersionedHashes,
+		ParentBeaconBlockRoot: item.ParentBeaconBlockRoot,
+		ExecutionRequests:     item.ExecutionRequests,
+	}
+}
+
+type importJob struct {
+	index  int
+	hash   Hash
+	item   ImportPayload
+	parent chan struct{}
+	done   chan struct{}
+}
+
+// ImportPayloads submits an ordered stream of payloads with a bounded worker
+// pool. A payload is only submitted once the newPayload of its parent, if
+// that parent came earlier in the stream, has returned, so the EL always sees
+// parents first; decoding and block hash verification still run in parallel.
+// Descendants of a payload that failed or was INVALID are not submitted.
+// After the stream ends, a single forkchoiceUpdated makes the last VALID
+// payload the head, safe and finalized block, unless cfg.SkipForkchoice is
+// set.
+func (c *EngineClient) ImportPayloads(ctx context.Context, payloads <-chan ImportPayload, cfg ImportConfig) (*ImportReport, error) {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	report := &ImportReport{Statuses: make(map[string]int)}
+	start := c.clock.Now()
+
+	var mu sync.Mutex
+	// pending maps the hash of every payload whose newPayload has not yet
+	// returned to a channel closed when it does
+	pending := make(map[Hash]chan struct{})
+	failed := make(map[Hash]bool)
+	lastIndex := -1
+
+	record := func(job *importJob, result ImportResult) {
+		mu.Lock()
+		delete(pending, job.hash)
+		if result.Err != nil || (result.Status.Status != StatusValid && result.Status.Final()) {
+			failed[job.hash] = true
+		}
+		if result.Err != nil {
+			report.Failed++
+		} else {
+			report.Submitted++
+			report.Statuses[result.Status.Status]++
+			if result.Status.Status == StatusValid && result.Index > lastIndex {
+				lastIndex, report.Head = result.Index, result.BlockHash
+			}
+		}
+		mu.Unlock()
+		close(job.done)
+		if cfg.OnResult != nil {
+			cfg.OnResult(result)
+		}
+	}
+
+	jobs := make(chan *importJob)
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				record(job, c.importOne(ctx, job, &mu, failed))
+			}
+		}()
+	}
+
+	index := 0
+	var dispatchErr error
+dispatch:
+	for {
+		var item ImportPayload
+		var ok bool
+		select {
+		case item, ok = <-payloads:
+		case <-ctx.Done():
+			dispatchErr = ctx.Err()
+			break dispatch
+		}
+		if !ok {
+			break
+		}
+		hash, _ := HexToHash(stringField(item.Payload, "blockHash"))
+		parent, _ := HexToHash(stringField(item.Payload, "parentHash"))
+		job := &importJob{index: index, hash: hash, item: item, done: make(chan struct{})}
+		index++
+		mu.Lock()
+		job.parent = pending[parent]
+		pending[hash] = job.done
+		mu.Unlock()
+
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			dispatchErr = ctx.Err()
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	report.Elapsed = c.clock.Now().Sub(start)
+	if dispatchErr != nil {
+		return report, dispatchErr
+	}
+
+	if lastIndex < 0 {
+		return report, fmt.Errorf("no payload was imported as VALID")
+	}
+	if cfg.SkipForkchoice {
+		return report, nil
+	}
+	return report, c.applyImportedHead(ctx, report)
+}
+
+// applyImportedHead makes the last VALID payload of an import the head, safe
+// and finalized block
+func (c *EngineClient) applyImportedHead(ctx context.Context, report *ImportReport) error {
+	state := ForkChoiceState{HeadBlockHash: report.Head, SafeBlockHash: report.Head, FinalizedBlockHash: report.Head}
+	response, err := c.ForkchoiceUpdated(ctx, state, nil)
+	report.Forkchoice = response
+	return err
+}
+
+func (c *EngineClient) importOne(ctx context.Context, job *importJob, mu *sync.Mutex, failed map[Hash]bool) ImportResult {
+	result := ImportResult{Index: job.index}
+	p, err := DecodeExecutionPayload(job.item.Payload)
+	if err == nil {
+		result.BlockHash = p.BlockHash
+		result.Number, err = decodeQuantity(p.BlockNumber)
+	}
+	if err == nil && c.verifyBlockHash {
+		var requestsHash *Hash
+		if job.item.ExecutionRequests != nil {
+			h, herr := RequestsHash(job.item.ExecutionRequests)
+			requestsHash, err = &h, herr
+		}
+		if err == nil {
+			err = VerifyBlockHash(p, job.item.ParentBeaconBlockRoot, requestsHash)
+		}
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("payload %d: %v", job.index, err)
+		return result
+	}
+
+	if job.parent != nil {
+		select {
+		case <-job.parent:
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+			return result
+		}
+	}
+	mu.Lock()
+	parentFailed := failed[p.ParentHash]
+	mu.Unlock()
+	if parentFailed {
+		result.Err = fmt.Errorf("block %d: parent %s was not imported", result.Number, p.ParentHash)
+		return result
+	}
+
+	start := c.clock.Now()
+	response, err := c.CallMethod(ctx, FamilyNewPayload, job.item.fork(), job.item.methodArgs())
+	result.Duration = c.clock.Now().Sub(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	var status PayloadStatus
+	if err := decodeResult(response, &status); err != nil {
+		result.Err = err
+		return result
+	}
+	result.Status = &status
+	return result
+}
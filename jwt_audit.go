@@ -0,0 +1,137 @@
+package engineclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Defaults used for the JWT_AUDIT_LOG environment variable
+const (
+	defaultAuditMaxSize    = 10 << 20
+	defaultAuditMaxBackups = 5
+)
+
+// JWTAuditEntry records one use of a signed token. The token itself is
+// never written, only its SHA-256, so the log cannot be replayed.
+type JWTAuditEntry struct {
+	Time        time.Time `json:"time"`
+	TokenSHA256 string    `json:"tokenSha256"`
+	IssuedAt    int64     `json:"iat"`
+	ExpiresAt   int64     `json:"exp"`
+	Endpoint    string    `json:"endpoint"`
+	Method      string    `json:"method"`
+	ID          uint64    `json:"id,omitempty"`
+	UUID        string    `json:"uuid,omitempty"`
+}
+
+// jwtAuditLog appends entries as JSON lines to a file, rotating it to
+// path.1, path.2, ... once it grows past maxSize
+type jwtAuditLog struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+func (a *jwtAuditLog) record(entry JWTAuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f != nil && a.maxSize > 0 && a.size+int64(len(line)) > a.maxSize {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	if a.f == nil {
+		f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open JWT audit log: %v", err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to stat JWT audit log: %v", err)
+		}
+		a.f, a.size = f, info.Size()
+	}
+	n, err := a.f.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write JWT audit log: %v", err)
+	}
+	return nil
+}
+
+// rotate closes the current file and shifts the backups up by one,
+// dropping the oldest
+func (a *jwtAuditLog) rotate() error {
+	a.f.Close()
+	a.f = nil
+	if a.maxBackups <= 0 {
+		return os.Remove(a.path)
+	}
+	os.Remove(fmt.Sprintf("%s.%d", a.path, a.maxBackups))
+	for i := a.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+	}
+	if err := os.Rename(a.path, a.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate JWT audit log: %v", err)
+	}
+	return nil
+}
+
+func (a *jwtAuditLog) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f != nil {
+		a.f.Close()
+		a.f = nil
+	}
+}
+
+// auditToken records that token authenticated call. Failures to write the
+// audit log are logged but never fail the call.
+func (c *EngineClient) auditToken(token string, call requestInfo) {
+	if c.audit == nil || token == "" {
+		return
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		c.logger.Warn("failed to parse token for audit log", "err", err)
+		return
+	}
+	iat, _ := claims["iat"].(float64)
+	exp, _ := claims["exp"].(float64)
+	endpoint := call.endpoint
+	if endpoint == "" {
+		endpoint = c.endpoint
+	}
+	sum := sha256.Sum256([]byte(token))
+	err := c.audit.record(JWTAuditEntry{
+		Time:        c.clock.Now(),
+		TokenSHA256: hex.EncodeToString(sum[:]),
+		IssuedAt:    int64(iat),
+		ExpiresAt:   int64(exp),
+		Endpoint:    endpoint,
+		Method:      call.method,
+		ID:          call.id,
+		UUID:        call.uuid,
+	})
+	if err != nil {
+		c.logger.Warn("failed to record JWT audit entry", "err", err)
+	}
+}
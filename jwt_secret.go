@@ -0,0 +1,209 @@
+package engineclient
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultSecretPollInterval is how often the CLI checks the secret file for
+// changes
+const defaultSecretPollInterval = 5 * time.Second
+
+// LoadJWTSecret reads a hex-encoded 32-byte secret in the format written for
+// geth's --authrpc.jwtsecret, with or without a 0x prefix
+func LoadJWTSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT secret: %v", err)
+	}
+	s := strings.TrimSpace(string(data))
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	secret, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT secret in %s: %v", path, err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("JWT secret in %s has %d bytes, want 32", path, len(secret))
+	}
+	return secret, nil
+}
+
+// NewEngineClientWithSecretFile builds a client whose signing key is read
+// from path and re-read whenever the file's size or modification time
+// changes, checked every interval. Rotated secrets take effect on the next
+// call without restarting. Close stops the watcher.
+func NewEngineClientWithSecretFile(endpoint, path string, interval time.Duration, opts ...Option) (*EngineClient, error) {
+	secret, err := LoadJWTSecret(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat JWT secret: %v", err)
+	}
+	c := NewEngineClient(endpoint, secret, opts...)
+	c.stopWatch = make(chan struct{})
+	go c.watchSecretFile(path, interval, info)
+	return c, nil
+}
+
+func (c *EngineClient) watchSecretFile(path string, interval time.Duration, last os.FileInfo) {
+	for {
+		tick := make(chan struct{})
+		stop := c.clock.AfterFunc(interval, func() { close(tick) })
+		select {
+		case <-c.stopWatch:
+			stop()
+			return
+		case <-tick:
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			c.logger.Warn("failed to stat JWT secret file", "path", path, "err", err)
+			continue
+		}
+		if info.Size() == last.Size() && info.ModTime().Equal(last.ModTime()) {
+			continue
+		}
+		secret, err := LoadJWTSecret(path)
+		if err != nil {
+			// Keep signing with the old key; the file may be mid-write.
+			c.logger.Warn("failed to reload JWT secret, keeping previous key", "path", path, "err", err)
+			continue
+		}
+		last = info
+		c.setJWTSecret(secret)
+		c.logger.Info("reloaded JWT secret", "path", path)
+	}
+}
+
+// setJWTSecret swaps the signing key and drops the token signed with the old
+// one
+func (c *EngineClient) setJWTSecret(secret []byte) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.jwtSecret = secret
+	c.token = ""
+}
+
+// Close stops the secret file watcher and closes the JWT audit log, if any
+func (c *EngineClient) Close() error {
+	c.closeOnce.Do(func() {
+		if c.stopWatch != nil {
+			close(c.stopWatch)
+		}
+		if c.audit != nil {
+			c.audit.close()
+		}
+		if c.keepAlive != nil {
+			c.stopKeepAlive()
+		}
+	})
+	return nil
+}
+
+// GenerateJWTSecret returns a cryptographically random 32-byte secret
+func GenerateJWTSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate JWT secret: %v", err)
+	}
+	return secret, nil
+}
+
+// WriteJWTSecretFile writes secret as 0x-prefixed hex, the format geth writes
+// for --authrpc.jwtsecret, readable only by the owner
+func WriteJWTSecretFile(path string, secret []byte, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists (use -force to overwrite)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create JWT secret file: %v", err)
+	}
+	if _, err := f.WriteString(encodeHex(secret)); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write JWT secret file: %v", err)
+	}
+	return f.Close()
+}
+
+// runJWT implements the jwt generate and jwt token subcommands
+func runJWT(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: engine-client jwt generate|token [flags]")
+	}
+	switch args[0] {
+	case "generate":
+		fs := flag.NewFlagSet("jwt generate", flag.ExitOnError)
+		path := fs.String("out", "jwt.hex", "file to write the secret to")
+		force := fs.Bool("force", false, "overwrite an existing file")
+		output := addOutputFlag(fs)
+		fs.Parse(args[1:])
+
+		out, err := newPrinter(*output)
+		if err != nil {
+			return err
+		}
+
+		secret, err := GenerateJWTSecret()
+		if err != nil {
+			return err
+		}
+		if err := WriteJWTSecretFile(*path, secret, *force); err != nil {
+			return err
+		}
+		out.emit(record{{"path", *path}}, func(w io.Writer) {
+			fmt.Fprintf(w, "Wrote JWT secret to %s\n", *path)
+		})
+		return nil
+	case "token":
+		fs := flag.NewFlagSet("jwt token", flag.ExitOnError)
+		config := addConfigFlags(fs)
+		secretFile := fs.String("secret", "", "JWT secret file (defaults to the configured jwtPath, then jwtSecret)")
+		output := addOutputFlag(fs)
+		fs.Parse(args[1:])
+
+		out, err := newPrinter(*output)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.resolve()
+		if err != nil {
+			return err
+		}
+		if *secretFile == "" {
+			*secretFile = cfg.JWTPath
+		}
+		var secret []byte
+		if *secretFile != "" {
+			if secret, err = LoadJWTSecret(*secretFile); err != nil {
+				return err
+			}
+		} else if cfg.JWTSecret != "" {
+			secret = []byte(cfg.JWTSecret)
+		} else {
+			return fmt.Errorf("no JWT secret: pass -secret or -jwt-path, or configure jwtPath or jwtSecret")
+		}
+		token, err := NewEngineClient("", secret).generateJWT()
+		if err != nil {
+			return fmt.Errorf("failed to sign token: %v", err)
+		}
+		out.emit(record{{"token", token}}, func(w io.Writer) { fmt.Fprintln(w, token) })
+		return nil
+	default:
+		return fmt.Errorf("unknown jwt command %q", args[0])
+	}
+}
@@ -0,0 +1,130 @@
+package engineclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// keepAliveTimeout bounds each liveness probe
+	keepAliveTimeout = 5 * time.Second
+	// codeMethodNotFound is the JSON-RPC error for an unknown method
+	codeMethodNotFound = -32601
+)
+
+// keepAlive probes the primary endpoint whenever it has gone interval
+// without a call, so a connection silently dropped by the EL or a NAT is
+// noticed before the next forkchoiceUpdated needs it
+type keepAlive struct {
+	interval  time.Duration
+	onFailure func(error)
+
+	mu     sync.Mutex
+	stop   func() bool
+	closed bool
+	// probe is the method sent, falling back to eth_chainId for ELs that
+	// do not implement engine_getClientVersionV1
+	probe   string
+	failing bool
+}
+
+// markActive records that the primary endpoint just answered
+func (c *EngineClient) markActive() {
+	c.lastActive.Store(c.clock.Now().UnixNano())
+}
+
+func (c *EngineClient) startKeepAlive() {
+	c.markActive()
+	c.scheduleKeepAlive(c.keepAlive.interval)
+}
+
+func (c *EngineClient) scheduleKeepAlive(d time.Duration) {
+	k := c.keepAlive
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.closed {
+		k.stop = c.clock.AfterFunc(d, c.keepAliveTick)
+	}
+}
+
+func (c *EngineClient) stopKeepAlive() {
+	k := c.keepAlive
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.closed = true
+	if k.stop != nil {
+		k.stop()
+	}
+}
+
+// keepAliveTick probes the endpoint if it has been idle for the whole
+// interval, and otherwise waits out the rest of it
+func (c *EngineClient) keepAliveTick() {
+	k := c.keepAlive
+	idle := c.clock.Now().Sub(time.Unix(0, c.lastActive.Load()))
+	if idle < k.interval {
+		c.scheduleKeepAlive(k.interval - idle)
+		return
+	}
+
+	timeout := keepAliveTimeout
+	if k.interval < timeout {
+		timeout = k.interval
+	}
+	ctx, cancel := c.withTimeout(context.Background(), timeout)
+	err := c.probeEndpoint(ctx)
+	cancel()
+
+	k.mu.Lock()
+	wasFailing := k.failing
+	k.failing = err != nil
+	k.mu.Unlock()
+	switch {
+	case err != nil:
+		// Drop pooled connections so the next call dials afresh rather
+		// than writing into one that is already dead.
+		if t, ok := c.client.Transport.(*http.Transport); ok {
+			t.CloseIdleConnections()
+		}
+		c.logger.Warn("keep-alive probe failed", "endpoint", c.endpoint, "err", err)
+		if k.onFailure != nil {
+			k.onFailure(err)
+		}
+	case wasFailing:
+		c.logger.Info("keep-alive probe succeeded again", "endpoint", c.endpoint)
+	}
+	c.scheduleKeepAlive(k.interval)
+}
+
+// probeEndpoint sends the lightest call the EL supports. Any JSON-RPC
+// answer, even an error, shows the endpoint is alive.
+func (c *EngineClient) probeEndpoint(ctx context.Context) error {
+	k := c.keepAlive
+	k.mu.Lock()
+	method := k.probe
+	k.mu.Unlock()
+
+	params := []interface{}{}
+	if method == "engine_getClientVersionV1" {
+		params = []interface{}{watchClientVersion}
+	}
+	// Send directly, skipping the response cache and retries, so the probe
+	// reaches the endpoint exactly once.
+	call := requestInfo{method: method, id: c.nextID.Add(1), endpoint: c.endpoint}
+	response, err := c.sendRequest(ctx, call, params)
+	if err != nil {
+		return err
+	}
+	var result interface{}
+	var rpcErr *RPCError
+	if errors.As(decodeResult(response, &result), &rpcErr) && rpcErr.Code == codeMethodNotFound && method != "eth_chainId" {
+		k.mu.Lock()
+		k.probe = "eth_chainId"
+		k.mu.Unlock()
+		return c.probeEndpoint(ctx)
+	}
+	return nil
+}
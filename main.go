@@ -1,144 +1,616 @@
-package main
+// Package engineclient is a client for the Ethereum Engine API, the
+// interface a consensus client drives an execution client through, and the
+// engine-client command built on it.
+package engineclient
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// EngineClient is safe for concurrent use by multiple goroutines. Options
+// must only be applied at construction time. A client built with an empty JWT
+// secret sends unauthenticated requests.
 type EngineClient struct {
-	endpoint  string
-	jwtSecret []byte
-	client    *http.Client
+	endpoint string
+	noAuth   bool
+	client   *http.Client
+	dialer   *endpointDialer
+
+	nextID       atomic.Uint64
+	requestUUIDs bool
+	logger       *slog.Logger
+
+	// tokenMu guards the signing key, which may be swapped by the secret
+	// file watcher, and the cached token signed with it
+	tokenMu     sync.Mutex
+	jwtSecret   []byte
+	token       string
+	tokenIssued time.Time
+	signer      TokenSigner
+	stopWatch   chan struct{}
+	closeOnce   sync.Once
+
+	verifyBlockHash bool
+	strictSchema    bool
+	heads           *headTracker
+	reorgHandler    func(ReorgEvent)
+	rewindHandler   func(RewindEvent, error)
+	forkchoiceStore ForkchoiceStore
+	cache           *responseCache
+	headers         http.Header
+	retryPolicy     *RetryPolicy
+	status          callStatus
+	audit           *jwtAuditLog
+	idPolicy        IDPolicy
+	balancer        *loadBalancer
+	captureHook     func(Capture)
+	timingHook      func(CallTiming)
+	timeouts        map[string]time.Duration
+	defaultTimeout  time.Duration
+	methods         *MethodRegistry
+	fork            Fork
+	payloadForks    payloadForks
+	valueTracker    *BlockValueTracker
+	chainGuard      *chainGuard
+	capabilityGuard *capabilityGuard
+	clock           Clock
+	keepAlive       *keepAlive
+	// lastActive is when the primary endpoint last answered, in Unix
+	// nanoseconds on the client's clock
+	lastActive atomic.Int64
 }
 
+// PayloadAttributes holds native values; MarshalJSON converts them to the
+// engine API's hex encoding
 type PayloadAttributes struct {
-	Timestamp             string `json:"timestamp"`
-	PrevRandao            string `json:"prevRandao"`
-	SuggestedFeeRecipient string `json:"suggestedFeeRecipient"`
+	Timestamp             time.Time    `json:"timestamp"`
+	PrevRandao            Hash         `json:"prevRandao"`
+	SuggestedFeeRecipient Address      `json:"suggestedFeeRecipient"`
+	Withdrawals           []Withdrawal `json:"withdrawals"`
+	ParentBeaconBlockRoot *Hash        `json:"parentBeaconBlockRoot,omitempty"`
 }
 
 type ForkChoiceState struct {
-	HeadBlockHash      string `json:"headBlockHash"`
-	SafeBlockHash      string `json:"safeBlockHash"`
-	FinalizedBlockHash string `json:"finalizedBlockHash"`
+	HeadBlockHash      Hash `json:"headBlockHash"`
+	SafeBlockHash      Hash `json:"safeBlockHash"`
+	FinalizedBlockHash Hash `json:"finalizedBlockHash"`
+}
+
+// ClientVersion identifies an execution or consensus client, as exchanged by
+// engine_getClientVersionV1
+type ClientVersion struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+type TransitionConfiguration struct {
+	TerminalTotalDifficulty string `json:"terminalTotalDifficulty"`
+	TerminalBlockHash       Hash   `json:"terminalBlockHash"`
+	TerminalBlockNumber     string `json:"terminalBlockNumber"`
 }
 
-func NewEngineClient(endpoint string, jwtSecret []byte) *EngineClient {
-	return &EngineClient{
+func NewEngineClient(endpoint string, jwtSecret []byte, opts ...Option) *EngineClient {
+	dialer := newEndpointDialer()
+	c := &EngineClient{
 		endpoint:  endpoint,
 		jwtSecret: jwtSecret,
-		client:    &http.Client{Timeout: 10 * time.Second},
+		client:    &http.Client{Transport: newTransport(dialer)},
+		dialer:    dialer,
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		heads:     newHeadTracker(),
+		methods:   DefaultMethodRegistry,
+		fork:      ForkParis,
+		clock:     realClock{},
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	if c.keepAlive != nil {
+		c.startKeepAlive()
+	}
+	return c
+}
+
+// maxIdleConnsPerHost lets parallel calls to the same EL reuse connections
+// instead of the default transport's limit of two idle connections
+const maxIdleConnsPerHost = 64
+
+func newTransport(dialer *endpointDialer) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = dialer.DialContext
+	t.MaxIdleConns = maxIdleConnsPerHost
+	t.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	return t
+}
+
+// tokenReuseWindow is how long a signed token is reused. ELs reject tokens
+// whose iat is more than 60 seconds from their clock.
+const tokenReuseWindow = 30 * time.Second
+
+// authToken returns a cached JWT, signing a new one once the cached token is
+// older than tokenReuseWindow. It returns an empty token when authentication
+// is disabled.
+func (c *EngineClient) authToken() (string, error) {
+	if c.noAuth {
+		return "", nil
+	}
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.signer == nil && len(c.jwtSecret) == 0 {
+		return "", nil
+	}
+	now := c.clock.Now()
+	if c.token != "" && now.Sub(c.tokenIssued) < tokenReuseWindow {
+		return c.token, nil
+	}
+	token, err := c.generateJWT()
+	if err != nil {
+		return "", err
+	}
+	c.token, c.tokenIssued = token, now
+	return token, nil
 }
 
 func (c *EngineClient) generateJWT() (string, error) {
 	claims := jwt.MapClaims{
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(time.Minute).Unix(),
+		"iat": c.clock.Now().Unix(),
+		"exp": c.clock.Now().Add(time.Minute).Unix(),
+	}
+	if c.signer != nil {
+		return c.signer.Sign(claims)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(c.jwtSecret)
+	return HS256Signer(c.jwtSecret).Sign(claims)
 }
 
+// makeRequest sends a JSON-RPC call, tagging its log lines and any returned
+// error with the request id and, if enabled, a UUID. A response carrying an
+// error member is returned as a *RequestError wrapping its *RPCError.
 func (c *EngineClient) makeRequest(ctx context.Context, method string, params interface{}) (map[string]interface{}, error) {
+	if err := c.guardCall(ctx, method); err != nil {
+		return nil, err
+	}
+	var cacheKey string
+	if c.cache != nil {
+		var ok bool
+		if cacheKey, ok = c.cache.key(method, params); ok {
+			if response, hit := c.cache.get(cacheKey, c.clock.Now()); hit {
+				c.logger.Debug("engine call served from cache", "method", method)
+				return response, nil
+			}
+		}
+	}
+
+	call := requestInfo{method: method, id: c.nextID.Add(1), endpoint: c.endpoint}
+	if c.requestUUIDs {
+		call.uuid = newUUID()
+	}
+
+	start := c.clock.Now()
+	result, err := c.withRetries(ctx, call, func() (map[string]interface{}, error) {
+		return c.withCallTimeout(ctx, method, func(ctx context.Context) (map[string]interface{}, error) {
+			if c.balancer == nil || !isReadMethod(method) || pinnedToPrimary(ctx) {
+				return c.sendRequest(ctx, call, params)
+			}
+			call.endpoint = c.balancer.pick(c.clock.Now())
+			result, err := c.sendRequest(ctx, call, params)
+			c.balancer.report(call.endpoint, err, c.clock.Now())
+			return result, err
+		})
+	})
+	attrs := append(call.logAttrs(), "duration", c.clock.Now().Sub(start))
+	if err != nil {
+		c.logger.Warn("engine call failed", append(attrs, "err", err)...)
+		return nil, &RequestError{Method: method, ID: call.id, UUID: call.uuid, Err: err}
+	}
+	if c.keepAlive != nil && call.endpoint == c.endpoint {
+		c.markActive()
+	}
+	if err := responseError(result); err != nil {
+		c.logger.Debug("engine call returned an error", append(attrs, "err", err)...)
+		return nil, &RequestError{Method: method, ID: call.id, UUID: call.uuid, Err: err}
+	}
+	c.logger.Debug("engine call", attrs...)
+	if c.valueTracker != nil && methodFamily(method) == "engine_getPayload" {
+		if value, ok := blockValueOf(result); ok {
+			c.valueTracker.Add(c.clock.Now(), call.endpoint, value)
+		}
+	}
+	if cacheKey != "" {
+		c.cache.put(cacheKey, method, result, c.clock.Now())
+	}
+	return result, nil
+}
+
+type requestInfo struct {
+	method   string
+	id       uint64
+	uuid     string
+	endpoint string
+}
+
+func (r requestInfo) logAttrs() []any {
+	attrs := []any{"method", r.method, "id", r.id, "endpoint", r.endpoint}
+	if r.uuid != "" {
+		attrs = append(attrs, "uuid", r.uuid)
+	}
+	return attrs
+}
+
+// newHTTPRequest encodes a JSON-RPC call and wraps it in an authenticated
+// HTTP request, returning the encoded body alongside it
+func (c *EngineClient) newHTTPRequest(ctx context.Context, call requestInfo, params interface{}) (*http.Request, []byte, error) {
 	// Create JSON-RPC request
 	request := map[string]interface{}{
 		"jsonrpc": "2.0",
-		"method":  method,
+		"method":  call.method,
 		"params":  params,
-		"id":      1,
+		"id":      call.id,
 	}
 
 	requestBody, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+		return nil, nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	token, err := c.generateJWT()
+	token, err := c.authToken()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewReader(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", call.endpoint, bytes.NewReader(requestBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		c.auditToken(token, call)
+	}
+	if call.uuid != "" {
+		req.Header.Set("X-Request-ID", call.uuid)
+	}
+	c.applyHeaders(ctx, req.Header)
+	return req, requestBody, nil
+}
+
+func (c *EngineClient) sendRequest(ctx context.Context, call requestInfo, params interface{}) (map[string]interface{}, error) {
+	req, requestBody, err := c.newHTTPRequest(ctx, call, params)
+	if err != nil {
+		return nil, err
+	}
 
 	// Make the request
+	tracer := newCallTracer()
+	req = req.WithContext(tracer.withTrace(req.Context()))
+	defer func() { c.reportTiming(tracer.timing(call)) }()
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %v", err)
+		err = fmt.Errorf("failed to make request: %w", wrapTimeout(err))
+		c.capture(call, req, requestBody, nil, nil, tracer.timing(call), err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	body := io.Reader(resp.Body)
+	var raw *bytes.Buffer
+	if c.captureHook != nil {
+		raw = &bytes.Buffer{}
+		body = io.TeeReader(resp.Body, raw)
+		defer func() {
+			// Capture whatever the decoder did not need to read.
+			io.Copy(io.Discard, io.LimitReader(body, maxCaptureTrailingBody))
+			c.capture(call, req, requestBody, resp, raw.Bytes(), tracer.timing(call), nil)
+		}()
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+		return nil, &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), c.clock.Now()),
+		}
 	}
 
 	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %v", err)
 	}
+	if err := checkResponseID(c.idPolicy, call.id, result); err != nil {
+		return nil, err
+	}
+
+	if c.strictSchema && result["error"] == nil {
+		if err := ValidateResult(call.method, result["result"]); err != nil {
+			return nil, err
+		}
+	}
 
 	return result, nil
 }
 
-// ForkchoiceUpdated sends a forkchoiceUpdated request
+// guardCall runs the one-off checks configured to precede a call: chain
+// verification before the first forkchoice update and the capability check
+// before the first engine call
+func (c *EngineClient) guardCall(ctx context.Context, method string) error {
+	if c.chainGuard != nil && methodFamily(method) == "engine_forkchoiceUpdated" {
+		if err := c.chainGuard.check(ctx, c); err != nil {
+			return err
+		}
+	}
+	if c.capabilityGuard != nil && strings.HasPrefix(method, "engine_") && method != "engine_exchangeCapabilities" {
+		c.capabilityGuard.check(ctx, c)
+	}
+	return nil
+}
+
+// ForkchoiceUpdated sends a forkchoiceUpdated request, in the version of the
+// client's fork or, when the attributes need a later one, the version that
+// accepts their shape. If the state was applied but persisting it to the
+// configured store fails, the response is returned together with the error.
 func (c *EngineClient) ForkchoiceUpdated(ctx context.Context, state ForkChoiceState, attributes *PayloadAttributes) (map[string]interface{}, error) {
-	params := []interface{}{state}
-	if attributes != nil {
-		params = append(params, attributes)
+	fork := c.fork
+	if attributes != nil && !forkAtLeast(fork, attributesFork(attributes)) {
+		fork = attributesFork(attributes)
 	}
-	return c.makeRequest(ctx, "engine_forkchoiceUpdatedV1", params)
+	return c.CallMethod(ctx, FamilyForkchoiceUpdated, fork, MethodArgs{State: &state, Attributes: attributes})
 }
 
-// NewPayload sends a newPayload request
+// observeForkchoice records the head of a forkchoice update the EL accepted
+// as VALID, reports a reorg if it does not extend the previous head, and
+// persists the state
+func (c *EngineClient) observeForkchoice(state ForkChoiceState, response map[string]interface{}) error {
+	var result ForkchoiceUpdatedResult
+	if err := decodeResult(response, &result); err != nil {
+		return nil
+	}
+	c.status.recordForkchoice(state, result.PayloadStatus, c.clock.Now())
+	if result.PayloadStatus.Status != StatusValid {
+		return nil
+	}
+	if event := c.heads.setHead(state.HeadBlockHash); event != nil && c.reorgHandler != nil {
+		c.reorgHandler(*event)
+	}
+	if c.forkchoiceStore != nil {
+		if err := c.forkchoiceStore.Save(state); err != nil {
+			return fmt.Errorf("forkchoice applied but not persisted: %v", err)
+		}
+	}
+	return nil
+}
+
+// NewPayload sends a newPayload request in the version for the payload's
+// fork: V2 for payloads with withdrawals and V1 otherwise. Cancun and later
+// payloads need blob hashes and a beacon root this method cannot carry, and
+// are refused; send them with CallMethod or NewPayloadV4.
 func (c *EngineClient) NewPayload(ctx context.Context, payload map[string]interface{}) (map[string]interface{}, error) {
-	return c.makeRequest(ctx, "engine_newPayloadV1", []interface{}{payload})
+	fork, err := payloadFork(payload)
+	if err != nil {
+		return nil, err
+	}
+	return c.CallMethod(ctx, FamilyNewPayload, fork, MethodArgs{Payload: payload})
 }
 
-func main() {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		fmt.Println("JWT_SECRET environment variable is not set")
-		return
+// payloadFork picks the newPayload fork from the fields a payload carries
+func payloadFork(payload map[string]interface{}) (Fork, error) {
+	if payload["blobGasUsed"] != nil || payload["excessBlobGas"] != nil {
+		return "", fmt.Errorf("payload has blob gas fields: send it with CallMethod and its %s arguments", ForkCancun)
+	}
+	if payload["withdrawals"] != nil {
+		return ForkShanghai, nil
 	}
+	return ForkParis, nil
+}
 
-	client := NewEngineClient(
-		"http://localhost:8551",
-		[]byte(jwtSecret),
-	)
+// GetPayload sends a getPayload request for a previously started build, in
+// the version for the fork its forkchoiceUpdated was sent at. Builds this
+// client did not start use the client's fork.
+func (c *EngineClient) GetPayload(ctx context.Context, payloadID string) (map[string]interface{}, error) {
+	fork, ok := c.payloadForks.lookup(payloadID)
+	if !ok {
+		fork = c.fork
+	}
+	return c.CallMethod(ctx, FamilyGetPayload, fork, MethodArgs{PayloadID: payloadID})
+}
+
+// NewPayloadV4 sends a Prague newPayload request along with its blob
+// versioned hashes, parent beacon block root and execution requests
+func (c *EngineClient) NewPayloadV4(ctx context.Context, payload map[string]interface{}, versionedHashes []Hash, parentBeaconBlockRoot Hash, executionRequests []string) (map[string]interface{}, error) {
+	return c.CallMethod(ctx, FamilyNewPayload, ForkPrague, MethodArgs{
+		Payload:               payload,
+		VersionedHashes:       versionedHashes,
+		ParentBeaconBlockRoot: &parentBeaconBlockRoot,
+		ExecutionRequests:     executionRequests,
+	})
+}
+
+// GetPayloadV4 sends a Prague getPayload request, whose envelope also carries
+// the block's execution requests
+func (c *EngineClient) GetPayloadV4(ctx context.Context, payloadID string) (map[string]interface{}, error) {
+	return c.CallMethod(ctx, FamilyGetPayload, ForkPrague, MethodArgs{PayloadID: payloadID})
+}
+
+// ExchangeTransitionConfiguration sends the legacy pre-merge
+// exchangeTransitionConfiguration request, which some custom devnets still
+// expect the CL to poll
+func (c *EngineClient) ExchangeTransitionConfiguration(ctx context.Context, config TransitionConfiguration) (map[string]interface{}, error) {
+	return c.makeRequest(ctx, "engine_exchangeTransitionConfigurationV1", []interface{}{config})
+}
+
+// GetPayloadBodiesByHash fetches the transactions and withdrawals of the
+// given blocks; unknown blocks come back as null entries
+func (c *EngineClient) GetPayloadBodiesByHash(ctx context.Context, hashes []Hash) (map[string]interface{}, error) {
+	return c.makeRequest(ctx, "engine_getPayloadBodiesByHashV1", []interface{}{hashes})
+}
+
+// GetBlockByHash sends eth_getBlockByHash, which ELs also serve on the
+// authenticated port
+func (c *EngineClient) GetBlockByHash(ctx context.Context, hash Hash, fullTransactions bool) (map[string]interface{}, error) {
+	return c.makeRequest(ctx, "eth_getBlockByHash", []interface{}{hash, fullTransactions})
+}
+
+// GetClientVersion identifies the caller to the EL and returns the EL's own
+// client versions
+func (c *EngineClient) GetClientVersion(ctx context.Context, version ClientVersion) (map[string]interface{}, error) {
+	return c.makeRequest(ctx, "engine_getClientVersionV1", []interface{}{version})
+}
+
+// responseError returns the error member of a response as an *RPCError, or
+// nil when there is none
+func responseError(response map[string]interface{}) error {
+	member, ok := response["error"]
+	if !ok || member == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(member)
+	rpcErr := &RPCError{}
+	if err := json.Unmarshal(raw, rpcErr); err != nil {
+		return fmt.Errorf("rpc error: %v", member)
+	}
+	return rpcErr
+}
+
+// decodeResult unmarshals the result member of a JSON-RPC response into out.
+// An error member is returned as an *RPCError.
+func decodeResult(response map[string]interface{}, out interface{}) error {
+	if err := responseError(response); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(response["result"])
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode result: %v", err)
+	}
+	return nil
+}
+
+// Call sends an arbitrary JSON-RPC request, for methods without a dedicated
+// wrapper
+func (c *EngineClient) Call(ctx context.Context, method string, params interface{}) (map[string]interface{}, error) {
+	return c.makeRequest(ctx, method, params)
+}
+
+const defaultEndpoint = "http://localhost:8551"
+
+// Main runs the engine-client command on os.Args and exits non-zero when
+// the command fails
+func Main() {
+	var err error
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		switch os.Args[1] {
+		case "repl":
+			err = runRepl(os.Args[2:])
+		case "bench":
+			err = runBench(os.Args[2:])
+		case "jwt":
+			err = runJWT(os.Args[2:])
+		case "follow":
+			err = runFollow(os.Args[2:])
+		case "shadow":
+			err = runShadow(os.Args[2:])
+		case "proxy":
+			err = runProxy(os.Args[2:])
+		case "capabilities":
+			err = runCapabilities(os.Args[2:])
+		case "payload":
+			err = runPayload(os.Args[2:])
+		case "import":
+			err = runImport(os.Args[2:])
+		case "export":
+			err = runExport(os.Args[2:])
+		case "check":
+			err = runCheck(os.Args[2:])
+		case "config":
+			err = runConfig(os.Args[2:])
+		case "watch":
+			err = runWatch(os.Args[2:])
+		case "scenario":
+			err = runScenario(os.Args[2:])
+		case "simulate":
+			err = runSimulate(os.Args[2:])
+		case "fuzz":
+			err = runFuzz(os.Args[2:])
+		default:
+			err = fmt.Errorf("unknown command %q", os.Args[1])
+		}
+	} else {
+		err = runDemo(os.Args[1:])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// runDemo sends a sample forkchoiceUpdated with payload attributes, the
+// command run when no subcommand is given
+func runDemo(args []string) error {
+	fs := flag.NewFlagSet("engine-client", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
 
 	forkChoice := ForkChoiceState{
-		HeadBlockHash:      "0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef",
-		SafeBlockHash:      "0xabcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890",
-		FinalizedBlockHash: "0x7890abcdef1234567890abcdef1234567890abcdef1234567890abcdef123456",
+		HeadBlockHash:      MustHexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef"),
+		SafeBlockHash:      MustHexToHash("0xabcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890"),
+		FinalizedBlockHash: MustHexToHash("0x7890abcdef1234567890abcdef1234567890abcdef1234567890abcdef123456"),
 	}
 
-	attributes := PayloadAttributes{
-		Timestamp:             fmt.Sprintf("0x%x", time.Now().Unix()),
-		PrevRandao:            "0xabcdefabcdefabcdefabcdefabcdefabcdefabcdefabcdefabcdefabcdef",
-		SuggestedFeeRecipient: "0xabc123abc123abc123abc123abc123abc123abc1",
+	attributes, err := NewPayloadAttributes().
+		WithTimestamp(client.clock.Now()).
+		WithRandao([32]byte{0xab, 0xcd, 0xef}).
+		WithFeeRecipient([20]byte{0xab, 0xc1, 0x23}).
+		Build()
+	if err != nil {
+		return fmt.Errorf("failed to build payload attributes: %v", err)
 	}
 
 	ctx := context.Background()
-	result, err := client.ForkchoiceUpdated(ctx, forkChoice, &attributes)
+	result, err := client.ForkchoiceUpdated(ctx, forkChoice, attributes)
 	if err != nil {
-		fmt.Printf("Error making forkchoice update request: %v\n", err)
-		return
+		return fmt.Errorf("forkchoice update failed: %w", err)
 	}
 
-	prettyResult, _ := json.MarshalIndent(result, "", "  ")
-	fmt.Printf("Forkchoice update result: %s\n", string(prettyResult))
+	var fcu ForkchoiceUpdatedResult
+	if err := decodeResult(result, &fcu); err != nil {
+		return err
+	}
+	rec := record{{"status", fcu.PayloadStatus.Status}, {"payloadId", fcu.PayloadID}, {"latestValidHash", fcu.PayloadStatus.LatestValidHash}}
+	out.emit(rec, func(w io.Writer) {
+		prettyResult, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintf(w, "Forkchoice update result: %s\n", prettyResult)
+	})
+	return statusError(fcu.PayloadStatus.Status, "forkchoice update is %s", fcu.PayloadStatus.Status)
 }
@@ -0,0 +1,322 @@
+package engineclient
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Option configures an EngineClient
+type Option func(*EngineClient)
+
+// WithBlockHashVerification makes NewPayload recompute the block hash from the
+// payload fields and refuse to submit payloads whose blockHash does not match
+func WithBlockHashVerification() Option {
+	return func(c *EngineClient) {
+		c.verifyBlockHash = true
+	}
+}
+
+// WithReorgHandler registers a callback invoked whenever a VALID
+// forkchoiceUpdated moves the head to a block that does not extend the
+// previous head. Ancestry is learned from payloads submitted via newPayload;
+// a head whose ancestry is unknown is not reported.
+// The handler runs on the calling goroutine of ForkchoiceUpdated.
+func WithReorgHandler(handler func(ReorgEvent)) Option {
+	return func(c *EngineClient) {
+		c.reorgHandler = handler
+	}
+}
+
+// WithAutoRewind makes the client recover from INVALID statuses that name a
+// latestValidHash. After a forkchoiceUpdated whose head is INVALID, and after
+// a newPayload whose INVALID status also invalidates the current head, it
+// sends a corrective forkchoiceUpdated back to the latest valid block with
+// RewindToLatestValid and passes the outcome to handler. The original
+// response is still returned to the caller.
+func WithAutoRewind(handler func(RewindEvent, error)) Option {
+	return func(c *EngineClient) {
+		c.rewindHandler = handler
+	}
+}
+
+// WithStrictSchemaValidation checks every successful response against the
+// embedded Engine API schema for its method and returns a
+// *SchemaViolationError when the EL deviates from the spec
+func WithStrictSchemaValidation() Option {
+	return func(c *EngineClient) {
+		c.strictSchema = true
+	}
+}
+
+// WithForkchoiceStore persists every forkchoice state the EL accepts as VALID
+// so it can be re-issued with ResumeForkchoice after a restart
+func WithForkchoiceStore(store ForkchoiceStore) Option {
+	return func(c *EngineClient) {
+		c.forkchoiceStore = store
+	}
+}
+
+// WithLogger sets the logger used for per-call log lines, each of which
+// carries the JSON-RPC id of the call
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *EngineClient) {
+		c.logger = logger
+	}
+}
+
+// WithRequestUUIDs additionally tags every call with a random UUID, sent to
+// the EL in the X-Request-ID header and included in logs and errors
+func WithRequestUUIDs() Option {
+	return func(c *EngineClient) {
+		c.requestUUIDs = true
+	}
+}
+
+// WithResponseCache keeps up to size responses to idempotent historical
+// queries in an LRU, each for the TTL configured for its method. A nil ttls
+// uses DefaultCacheTTLs; methods without a positive TTL are never cached.
+func WithResponseCache(size int, ttls map[string]time.Duration) Option {
+	return func(c *EngineClient) {
+		if ttls == nil {
+			ttls = DefaultCacheTTLs
+		}
+		c.cache = newResponseCache(size, ttls)
+	}
+}
+
+// WithHeader adds an HTTP header to every request, for reverse proxies in
+// front of the authrpc port that need their own credentials or forwarding
+// headers. Extra headers are applied after the JWT, so setting Authorization
+// replaces it.
+func WithHeader(key, value string) Option {
+	return func(c *EngineClient) {
+		if c.headers == nil {
+			c.headers = http.Header{}
+		}
+		c.headers.Add(key, value)
+	}
+}
+
+// WithRetryPolicy retries failed calls according to policy, honouring any
+// Retry-After sent with a retried reply. Without it calls are attempted
+// once.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *EngineClient) {
+		c.retryPolicy = &policy
+	}
+}
+
+// WithJWTAuditLog appends the iat, exp and hash of the token used for every
+// request, along with the request it authenticated, to a JSON lines file at
+// path. The file is rotated once it exceeds maxSize bytes, keeping
+// maxBackups old files.
+func WithJWTAuditLog(path string, maxSize int64, maxBackups int) Option {
+	return func(c *EngineClient) {
+		c.audit = &jwtAuditLog{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	}
+}
+
+// WithIDPolicy sets which response ids are accepted as answering a request,
+// for interop with proxies that rewrite or drop JSON-RPC ids
+func WithIDPolicy(policy IDPolicy) Option {
+	return func(c *EngineClient) {
+		c.idPolicy = policy
+	}
+}
+
+// WithReadEndpoints spreads stateless read calls, such as getPayloadBodies
+// and eth_getBlockByHash, round-robin across the primary endpoint and the
+// given ones. Endpoints that fail repeatedly are skipped for a while.
+// Stateful engine calls always go to the primary.
+func WithReadEndpoints(endpoints ...string) Option {
+	return func(c *EngineClient) {
+		c.balancer = newLoadBalancer(append([]string{c.endpoint}, endpoints...))
+	}
+}
+
+// WithCaptureHook calls hook with the raw bytes, status and headers of
+// every HTTP exchange, including retries, without affecting the values
+// returned by the client. The hook runs on the calling goroutine.
+func WithCaptureHook(hook func(Capture)) Option {
+	return func(c *EngineClient) {
+		c.captureHook = hook
+	}
+}
+
+// WithTimingHook calls hook with the DNS, connect, TLS, time-to-first-byte
+// and decode breakdown of every HTTP exchange, including retries, for export
+// to a metrics system. The same breakdown is logged at debug level. The hook
+// runs on the calling goroutine.
+func WithTimingHook(hook func(CallTiming)) Option {
+	return func(c *EngineClient) {
+		c.timingHook = hook
+	}
+}
+
+// WithMethodTimeout overrides the per-attempt timeout for method, which may
+// name one version, such as engine_getPayloadV4, or a whole family, such as
+// engine_getPayload. Methods without an override use SpecTimeouts.
+func WithMethodTimeout(method string, timeout time.Duration) Option {
+	return func(c *EngineClient) {
+		if c.timeouts == nil {
+			c.timeouts = make(map[string]time.Duration)
+		}
+		c.timeouts[method] = timeout
+	}
+}
+
+// WithDefaultTimeout sets the per-attempt timeout for methods that have
+// neither an override nor a spec limit, which is 10s otherwise
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(c *EngineClient) {
+		c.defaultTimeout = timeout
+	}
+}
+
+// WithMethodRegistry resolves the engine methods, CallMethod, import and
+// shadow calls through registry instead of DefaultMethodRegistry, so methods
+// registered on it affect only this client
+func WithMethodRegistry(registry *MethodRegistry) Option {
+	return func(c *EngineClient) {
+		c.methods = registry
+	}
+}
+
+// WithFork sets the fork the EL runs, which picks the forkchoiceUpdated
+// version for calls without payload attributes, including rewinds. Calls with
+// attributes use the version that accepts their shape. The default is
+// ForkParis.
+func WithFork(fork Fork) Option {
+	return func(c *EngineClient) {
+		c.fork = fork
+	}
+}
+
+// WithTokenSigner signs engine API tokens with signer instead of the
+// client's JWT secret, for keys held in an HSM, KMS or remote signer. Signed
+// tokens are still reused for up to 30 seconds.
+func WithTokenSigner(signer TokenSigner) Option {
+	return func(c *EngineClient) {
+		c.signer = signer
+	}
+}
+
+// WithProxy routes engine traffic, including the follower's websocket,
+// through the proxy at rawURL: socks5://, socks5h:// or an http:// or
+// https:// proxy supporting CONNECT, with optional user:password. Without
+// it the client honours HTTP_PROXY, HTTPS_PROXY and NO_PROXY. An invalid URL
+// makes every call fail with the parse error.
+func WithProxy(rawURL string) Option {
+	return func(c *EngineClient) {
+		t, ok := c.client.Transport.(*http.Transport)
+		if !ok {
+			return
+		}
+		proxy, err := parseProxyURL(rawURL)
+		t.Proxy = func(*http.Request) (*url.URL, error) { return proxy, err }
+	}
+}
+
+// WithPinnedIP connects to the primary endpoint's host at ip instead of
+// resolving it, while TLS still verifies the hostname. An invalid address
+// makes every call fail.
+func WithPinnedIP(ip string) Option {
+	return func(c *EngineClient) {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			c.dialer.pinErr = fmt.Errorf("invalid pinned IP %q", ip)
+			return
+		}
+		if u, err := url.Parse(c.endpoint); err == nil {
+			c.dialer.pins[u.Hostname()] = parsed
+		}
+	}
+}
+
+// WithDialFallbackDelay sets how long each connection attempt to one of a
+// host's addresses runs before the next address is tried alongside it.
+// Hosts resolving to several A and AAAA records are dialed this way.
+func WithDialFallbackDelay(delay time.Duration) Option {
+	return func(c *EngineClient) {
+		c.dialer.fallbackDelay = delay
+	}
+}
+
+// WithKeepAlive probes the primary endpoint with engine_getClientVersionV1,
+// or eth_chainId where that is not implemented, whenever interval passes
+// without a successful call. A failed probe closes the pooled connections,
+// so the next call reconnects, and is passed to onFailure if it is set. The
+// probes stop when the client is closed.
+func WithKeepAlive(interval time.Duration, onFailure func(error)) Option {
+	return func(c *EngineClient) {
+		if interval <= 0 {
+			c.keepAlive = nil
+			return
+		}
+		c.keepAlive = &keepAlive{interval: interval, onFailure: onFailure, probe: "engine_getClientVersionV1"}
+	}
+}
+
+// WithoutProxy connects directly even when proxy environment variables are
+// set
+func WithoutProxy() Option {
+	return func(c *EngineClient) {
+		if t, ok := c.client.Transport.(*http.Transport); ok {
+			t.Proxy = nil
+		}
+	}
+}
+
+// WithBlockValueTracker records the blockValue of every getPayload response
+// the client receives in tracker
+func WithBlockValueTracker(tracker *BlockValueTracker) Option {
+	return func(c *EngineClient) {
+		c.valueTracker = tracker
+	}
+}
+
+// WithChainVerification makes the client run VerifyChain before its first
+// forkchoiceUpdated and refuse to send any until the EL is confirmed to be on
+// the given chain, so a misconfigured endpoint cannot move another network's
+// head. A zero chainID or genesisHash skips that part of the check.
+func WithChainVerification(chainID uint64, genesisHash Hash) Option {
+	return func(c *EngineClient) {
+		c.chainGuard = &chainGuard{network: Network{ChainID: chainID, GenesisHash: genesisHash}}
+	}
+}
+
+// WithCapabilityCheck makes the client run CheckCapabilities for forks, or
+// for the client's fork when none are given, before its first engine call,
+// so an EL missing methods the registry resolves is noticed before one of
+// them is needed. Only that first call waits for the check, and one that
+// cannot reach the EL is retried with a backoff. Missing methods are logged
+// as warnings and the report is passed to handler, if set; calls go ahead
+// either way.
+func WithCapabilityCheck(handler func(*CapabilityReport, error), forks ...Fork) Option {
+	return func(c *EngineClient) {
+		c.capabilityGuard = &capabilityGuard{forks: forks, handler: handler}
+	}
+}
+
+// WithClock replaces the system clock used for JWT claims, token reuse,
+// retry and await backoff, build waits, timeouts, cache and endpoint
+// cooldown expiry, secret file polling and recorded durations, so tests can
+// control time with a ManualClock. Network tracing and dial fallback still
+// use the system clock.
+func WithClock(clock Clock) Option {
+	return func(c *EngineClient) {
+		c.clock = clock
+	}
+}
+
+// WithoutAuth skips JWT generation entirely, for ELs run with authentication
+// disabled on local devnets
+func WithoutAuth() Option {
+	return func(c *EngineClient) {
+		c.noAuth = true
+	}
+}
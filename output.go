@@ -0,0 +1,269 @@
+package engineclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CLI output formats. text is the human-readable default; json writes one
+// object per line and yaml one document per record, both with fields in a
+// fixed order; table aligns records in columns; quiet prints nothing, leaving
+// only the exit code.
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputTable = "table"
+	outputQuiet = "quiet"
+)
+
+// Exit codes, so scripts can tell failures apart without parsing output
+const (
+	exitError   = 1
+	exitInvalid = 2
+)
+
+// exitCodeError carries the process exit code for an error returned by a
+// command
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// exitCode maps a command's error to the code the process exits with
+func exitCode(err error) int {
+	var coded *exitCodeError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return exitError
+}
+
+// statusError returns an error exiting with exitInvalid when the EL judged a
+// payload INVALID or INVALID_BLOCK_HASH, and nil for any other status
+func statusError(status string, format string, args ...interface{}) error {
+	if status != StatusInvalid && status != StatusInvalidBlockHash {
+		return nil
+	}
+	return &exitCodeError{code: exitInvalid, err: fmt.Errorf(format, args...)}
+}
+
+// field is one named value of an output record
+type field struct {
+	key   string
+	value interface{}
+}
+
+// record is a row of command output whose fields always print in this order
+type record []field
+
+func (r record) keys() string {
+	keys := make([]string, len(r))
+	for i, f := range r {
+		keys[i] = f.key
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// printer renders command output in the format chosen with -output. It is
+// safe for use from the goroutines commands report progress on.
+type printer struct {
+	format string
+	w      io.Writer
+
+	mu sync.Mutex
+	// table columns are sized by the header and the first row, so rows
+	// streamed later line up without buffering the whole output, unless the
+	// command batches its rows to size columns over all of them
+	header  string
+	widths  []int
+	batched bool
+	pending []record
+}
+
+// addOutputFlag registers -output on a command's flag set
+func addOutputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", outputText, "output format: text, json, yaml, table or quiet")
+}
+
+func newPrinter(format string) (*printer, error) {
+	switch format {
+	case outputText, outputJSON, outputYAML, outputTable, outputQuiet:
+		return &printer{format: format, w: os.Stdout}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// emit prints rec, calling text to render it in the default format
+func (p *printer) emit(rec record, text func(w io.Writer)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.format {
+	case outputText:
+		if text != nil {
+			text(p.w)
+		}
+	case outputJSON:
+		p.w.Write(append(encodeRecordJSON(rec), '\n'))
+	case outputYAML:
+		var buf bytes.Buffer
+		buf.WriteString("---\n")
+		for _, f := range rec {
+			fmt.Fprintf(&buf, "%s: %s\n", f.key, encodeValue(f.value))
+		}
+		p.w.Write(buf.Bytes())
+	case outputTable:
+		if p.batched && len(rec) > 0 {
+			p.pending = append(p.pending, rec)
+			return
+		}
+		p.writeRows([]record{rec})
+	}
+}
+
+// batch holds table rows back until the returned function is called, so
+// columns fit every row. Commands with a bounded result use it as
+// defer out.batch()().
+func (p *printer) batch() func() {
+	p.mu.Lock()
+	p.batched = true
+	p.mu.Unlock()
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.batched = false
+		// Size each run of rows with the same fields as one table.
+		for len(p.pending) > 0 {
+			n := 1
+			for n < len(p.pending) && p.pending[n].keys() == p.pending[0].keys() {
+				n++
+			}
+			p.writeRows(p.pending[:n])
+			p.pending = p.pending[n:]
+		}
+	}
+}
+
+// note prints an informational line in the text format only
+func (p *printer) note(format string, args ...interface{}) {
+	p.emit(nil, func(w io.Writer) { fmt.Fprintf(w, format+"\n", args...) })
+}
+
+// writeRows prints rows sharing the same fields, starting a new table when
+// they differ from the last one printed
+func (p *printer) writeRows(rows []record) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return
+	}
+	cells := make([][]string, len(rows))
+	for i, rec := range rows {
+		cells[i] = make([]string, len(rec))
+		for j, f := range rec {
+			cells[i][j] = cellText(f.value)
+		}
+	}
+	// A record with different fields, such as a closing summary, starts a new
+	// table.
+	if keys := rows[0].keys(); keys != p.header {
+		if p.header != "" {
+			fmt.Fprintln(p.w)
+		}
+		p.header = keys
+		headers := make([]string, len(rows[0]))
+		p.widths = make([]int, len(headers))
+		for j, f := range rows[0] {
+			headers[j] = strings.ToUpper(f.key)
+			p.widths[j] = len(headers[j])
+			for i := range cells {
+				p.widths[j] = max(p.widths[j], len(cells[i][j]))
+			}
+		}
+		p.writeCells(headers)
+	}
+	for _, row := range cells {
+		p.writeCells(row)
+	}
+}
+
+func (p *printer) writeCells(cells []string) {
+	var sb strings.Builder
+	for i, cell := range cells {
+		if i == len(cells)-1 {
+			sb.WriteString(cell)
+			break
+		}
+		fmt.Fprintf(&sb, "%-*s  ", p.widths[i], cell)
+	}
+	fmt.Fprintln(p.w, strings.TrimRight(sb.String(), " "))
+}
+
+func encodeRecordJSON(rec record) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range rec {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.WriteString(encodeValue(f.value))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// encodeValue renders a field as JSON, which is also valid as a YAML flow
+// scalar or collection. Durations and errors become strings.
+func encodeValue(v interface{}) string {
+	switch x := v.(type) {
+	case time.Duration:
+		v = x.String()
+	case error:
+		v = x.Error()
+	}
+	// Encode without HTML escaping so values such as <missing> stay readable.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		buf.Reset()
+		enc.Encode(fmt.Sprint(v))
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func cellText(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		if x == "" {
+			return "-"
+		}
+		return x
+	case time.Duration:
+		return x.String()
+	case error:
+		return x.Error()
+	case fmt.Stringer:
+		return x.String()
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Struct:
+		return encodeValue(v)
+	}
+	return fmt.Sprint(v)
+}
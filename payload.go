@@ -0,0 +1,76 @@
+package engineclient
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type Withdrawal struct {
+	Index          string  `json:"index"`
+	ValidatorIndex string  `json:"validatorIndex"`
+	Address        Address `json:"address"`
+	Amount         string  `json:"amount"`
+}
+
+type ExecutionPayload struct {
+	ParentHash    Hash         `json:"parentHash"`
+	FeeRecipient  Address      `json:"feeRecipient"`
+	StateRoot     Hash         `json:"stateRoot"`
+	ReceiptsRoot  Hash         `json:"receiptsRoot"`
+	LogsBloom     string       `json:"logsBloom"`
+	PrevRandao    Hash         `json:"prevRandao"`
+	BlockNumber   string       `json:"blockNumber"`
+	GasLimit      string       `json:"gasLimit"`
+	GasUsed       string       `json:"gasUsed"`
+	Timestamp     string       `json:"timestamp"`
+	ExtraData     string       `json:"extraData"`
+	BaseFeePerGas string       `json:"baseFeePerGas"`
+	BlockHash     Hash         `json:"blockHash"`
+	Transactions  []string     `json:"transactions"`
+	Withdrawals   []Withdrawal `json:"withdrawals"`
+	BlobGasUsed   *string      `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas *string      `json:"excessBlobGas,omitempty"`
+}
+
+// DecodeExecutionPayload converts a loosely typed payload, such as the
+// executionPayload member of a getPayload response, into an ExecutionPayload
+func DecodeExecutionPayload(payload map[string]interface{}) (*ExecutionPayload, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	var p ExecutionPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %v", err)
+	}
+	return &p, nil
+}
+
+// Payload status values returned by newPayload and forkchoiceUpdated
+const (
+	StatusValid            = "VALID"
+	StatusInvalid          = "INVALID"
+	StatusSyncing          = "SYNCING"
+	StatusAccepted         = "ACCEPTED"
+	StatusInvalidBlockHash = "INVALID_BLOCK_HASH"
+)
+
+type PayloadStatus struct {
+	Status          string  `json:"status"`
+	LatestValidHash *Hash   `json:"latestValidHash"`
+	ValidationError *string `json:"validationError"`
+}
+
+// Final reports whether the status is a definitive verdict on the payload
+func (s *PayloadStatus) Final() bool {
+	switch s.Status {
+	case StatusValid, StatusInvalid, StatusInvalidBlockHash:
+		return true
+	}
+	return false
+}
+
+type ForkchoiceUpdatedResult struct {
+	PayloadStatus PayloadStatus `json:"payloadStatus"`
+	PayloadID     *string       `json:"payloadId"`
+}
@@ -0,0 +1,312 @@
+package engineclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// maxProxyBodySize bounds request and response bodies relayed by the proxy;
+// getPayloadBodies responses can be large
+const maxProxyBodySize = 128 << 20
+
+// jwtIatTolerance is how far a token's iat may be from the local clock, as
+// required by the engine API authentication spec
+const jwtIatTolerance = 60 * time.Second
+
+// ProxyRecord is one relayed exchange, written as a JSON line when recording
+type ProxyRecord struct {
+	Time     time.Time       `json:"time"`
+	Remote   string          `json:"remote"`
+	Methods  []string        `json:"methods"`
+	Status   int             `json:"status"`
+	Duration string          `json:"duration"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// ProxyServer is a transparent engine API tap: it authenticates incoming
+// calls with a JWT secret, forwards the body unchanged to the upstream EL
+// with a freshly signed token, and relays the EL's reply byte for byte
+type ProxyServer struct {
+	upstream *EngineClient
+	// inbound is the secret incoming tokens must be signed with; when nil
+	// the upstream client's current secret is used
+	inbound []byte
+
+	recordMu sync.Mutex
+	record   io.Writer
+}
+
+// NewProxyServer creates a proxy in front of upstream. Incoming calls must
+// carry a token signed with inboundSecret or, when that is empty, with the
+// upstream client's own JWT secret. It fails when there is neither, as for
+// a client signing with a TokenSigner or with authentication disabled,
+// since relaying unauthenticated calls under the client's credentials would
+// open the EL to anyone who can reach the proxy. If record is non-nil every
+// exchange is appended to it as a ProxyRecord.
+func NewProxyServer(upstream *EngineClient, inboundSecret []byte, record io.Writer) (*ProxyServer, error) {
+	p := &ProxyServer{upstream: upstream, inbound: inboundSecret, record: record}
+	if len(p.inboundSecret()) == 0 {
+		return nil, fmt.Errorf("proxy needs a JWT secret to verify incoming calls")
+	}
+	return p, nil
+}
+
+// inboundSecret returns the secret incoming tokens are checked against,
+// following the upstream client's secret file when it has one
+func (p *ProxyServer) inboundSecret() []byte {
+	if len(p.inbound) > 0 {
+		return p.inbound
+	}
+	c := p.upstream
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.noAuth || c.signer != nil {
+		return nil
+	}
+	return c.jwtSecret
+}
+
+func (p *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := p.verifyToken(r.Header.Get("Authorization")); err != nil {
+		p.upstream.logger.Warn("rejected proxied call", "remote", r.RemoteAddr, "err", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxProxyBodySize+1))
+	if err != nil || len(body) > maxProxyBodySize {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	start := p.upstream.clock.Now()
+	status, response, err := p.upstream.forward(r.Context(), body)
+	rec := ProxyRecord{
+		Time:     start,
+		Remote:   r.RemoteAddr,
+		Methods:  rpcMethods(body),
+		Status:   status,
+		Duration: p.upstream.clock.Now().Sub(start).String(),
+		Request:  rawJSON(body),
+		Response: rawJSON(response),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+		p.upstream.logger.Warn("proxied call failed", "methods", rec.Methods, "err", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	} else {
+		p.upstream.logger.Info("proxied call", "methods", rec.Methods, "status", status, "duration", rec.Duration)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(response)
+	}
+	p.writeRecord(rec)
+}
+
+func (p *ProxyServer) writeRecord(rec ProxyRecord) {
+	if p.record == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	p.recordMu.Lock()
+	defer p.recordMu.Unlock()
+	p.record.Write(append(line, '\n'))
+}
+
+// verifyToken checks an incoming Authorization header against the inbound
+// secret
+func (p *ProxyServer) verifyToken(header string) error {
+	secret := p.inboundSecret()
+	if len(secret) == 0 {
+		return fmt.Errorf("no secret to verify tokens with")
+	}
+	now := p.upstream.clock.Now()
+
+	raw, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return fmt.Errorf("missing bearer token")
+	}
+	// Expiry is checked below against the client's clock rather than by the
+	// parser against the system clock.
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return secret, nil
+	})
+	if err != nil {
+		return fmt.Errorf("invalid token: %v", err)
+	}
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return fmt.Errorf("token has no iat claim")
+	}
+	if skew := now.Sub(time.Unix(int64(iat), 0)); skew > jwtIatTolerance || skew < -jwtIatTolerance {
+		return fmt.Errorf("token iat is %s from local time", skew.Round(time.Second))
+	}
+	if exp, ok := claims["exp"].(float64); ok && !now.Before(time.Unix(int64(exp), 0)) {
+		return fmt.Errorf("token is expired")
+	}
+	return nil
+}
+
+// forward posts an already encoded JSON-RPC body to the endpoint and returns
+// the raw response, without interpreting either
+func (c *EngineClient) forward(ctx context.Context, body []byte) (int, []byte, error) {
+	// A batch gets the longest limit of the methods it contains
+	methods := rpcMethods(body)
+	var limit time.Duration
+	for _, method := range methods {
+		d, _ := c.callTimeout(method)
+		limit = max(limit, d)
+	}
+	if limit == 0 {
+		limit = defaultCallTimeout
+	}
+	ctx, cancel := c.withTimeout(ctx, limit)
+	defer cancel()
+
+	token, err := c.authToken()
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		c.auditToken(token, requestInfo{method: strings.Join(methods, ",")})
+	}
+	c.applyHeaders(ctx, req.Header)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to make request: %w", wrapTimeout(err))
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(io.LimitReader(resp.Body, maxProxyBodySize))
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	return resp.StatusCode, response, nil
+}
+
+// rpcMethods lists the methods named in a single or batch JSON-RPC body
+func rpcMethods(body []byte) []string {
+	type call struct {
+		Method string `json:"method"`
+	}
+	var batch []call
+	if err := json.Unmarshal(body, &batch); err != nil {
+		var single call
+		if json.Unmarshal(body, &single) != nil {
+			return nil
+		}
+		batch = []call{single}
+	}
+	methods := make([]string, len(batch))
+	for i, c := range batch {
+		methods[i] = c.Method
+	}
+	return methods
+}
+
+// rawJSON embeds b in a record as-is when it is valid JSON and as a string
+// otherwise
+func rawJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	if json.Valid(b) {
+		return b
+	}
+	quoted, _ := json.Marshal(string(b))
+	return quoted
+}
+
+// runProxy listens on its own authrpc port and relays every call to the
+// upstream EL, logging and optionally recording each exchange
+func runProxy(args []string) error {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8552", "address to accept engine API calls on")
+	config := addConfigFlags(fs)
+	recordPath := fs.String("record", "", "append every exchange to this JSONL file")
+	inboundPath := fs.String("inbound-jwt-path", "", "JWT secret file incoming calls must be signed with (defaults to the upstream secret)")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	if _, err := newPrinter(*output); err != nil {
+		return err
+	}
+	// The proxy only logs, so -output picks the log format: json logs as JSON
+	// lines, quiet discards them and the rest log as text.
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, nil)
+	switch *output {
+	case outputJSON:
+		handler = slog.NewJSONHandler(os.Stderr, nil)
+	case outputQuiet:
+		handler = slog.NewTextHandler(io.Discard, nil)
+	}
+	logger := slog.New(handler)
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient(WithLogger(logger))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var inbound []byte
+	if *inboundPath != "" {
+		if inbound, err = LoadJWTSecret(*inboundPath); err != nil {
+			return err
+		}
+	}
+	var record io.Writer
+	if *recordPath != "" {
+		f, err := os.OpenFile(*recordPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open record file: %v", err)
+		}
+		defer f.Close()
+		record = f
+	}
+
+	proxy, err := NewProxyServer(client, inbound, record)
+	if err != nil {
+		return fmt.Errorf("%v: set jwtPath or -inbound-jwt-path", err)
+	}
+
+	logger.Info("proxying engine API", "listen", *listen, "upstream", cfg.Endpoint)
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           proxy,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
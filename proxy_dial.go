@@ -0,0 +1,222 @@
+package engineclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// parseProxyURL accepts the proxy schemes both the HTTP transport and the
+// websocket dialer can use
+func parseProxyURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", rawURL)
+	}
+	return u, nil
+}
+
+// proxyFor returns the proxy the client's transport would use for target,
+// or nil for a direct connection. Websocket URLs are looked up as their
+// http and https equivalents, so HTTP_PROXY and HTTPS_PROXY apply to them.
+func (c *EngineClient) proxyFor(target *url.URL) (*url.URL, error) {
+	t, ok := c.client.Transport.(*http.Transport)
+	if !ok || t.Proxy == nil {
+		return nil, nil
+	}
+	u := *target
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	return t.Proxy(&http.Request{URL: &u, Header: http.Header{}})
+}
+
+// dialProxy opens a TCP tunnel to addr through a SOCKS5 or HTTP CONNECT proxy
+func dialProxy(ctx context.Context, proxy *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		port := "1080"
+		switch proxy.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), port)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial proxy %s: %w", proxyAddr, wrapTimeout(err))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Close the connection if ctx ends mid-handshake, unblocking any read.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	switch proxy.Scheme {
+	case "socks5", "socks5h":
+		err = socks5Connect(conn, proxy.User, addr)
+	case "https":
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err == nil {
+			conn = tlsConn
+			err = httpConnect(conn, proxy.User, addr)
+		}
+	default:
+		err = httpConnect(conn, proxy.User, addr)
+	}
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("proxy handshake with %s: %w", proxyAddr, wrapTimeout(ctx.Err()))
+		}
+		return nil, fmt.Errorf("proxy handshake with %s: %v", proxyAddr, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// socks5Connect performs an RFC 1928 CONNECT, authenticating with RFC 1929
+// username and password when the proxy URL carries them. The target host is
+// always sent by name, leaving resolution to the proxy.
+func socks5Connect(conn net.Conn, user *url.Userinfo, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	methods := []byte{0x00}
+	if user != nil {
+		methods = []byte{0x02}
+	}
+	if _, err := conn.Write(append([]byte{5, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	if reply[0] != 5 {
+		return fmt.Errorf("not a SOCKS5 proxy")
+	}
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		password, _ := user.Password()
+		name := user.Username()
+		if len(name) > 255 || len(password) > 255 {
+			return fmt.Errorf("SOCKS5 credentials too long")
+		}
+		auth := append([]byte{1, byte(len(name))}, name...)
+		auth = append(append(auth, byte(len(password))), password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply[:]); err != nil {
+			return err
+		}
+		if reply[1] != 0 {
+			return fmt.Errorf("SOCKS5 authentication failed")
+		}
+	default:
+		return fmt.Errorf("SOCKS5 proxy accepts none of the offered auth methods")
+	}
+
+	req := []byte{5, 1, 0}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+		req = append(append(req, 1), ip.To4()...)
+	} else if ip != nil {
+		req = append(append(req, 4), ip.To16()...)
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long")
+		}
+		req = append(append(req, 3, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	var head [4]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return err
+	}
+	if head[1] != 0 {
+		return fmt.Errorf("SOCKS5 connect to %s failed with code %d", addr, head[1])
+	}
+	var skip int
+	switch head[3] {
+	case 1:
+		skip = net.IPv4len
+	case 4:
+		skip = net.IPv6len
+	case 3:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return err
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("SOCKS5 reply has unknown address type %d", head[3])
+	}
+	// Discard the bound address and port.
+	_, err = io.CopyN(io.Discard, conn, int64(skip+2))
+	return err
+}
+
+// httpConnect asks an HTTP proxy to open a tunnel with CONNECT
+func httpConnect(conn net.Conn, user *url.Userinfo, addr string) error {
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+	// The proxy sends nothing past its reply until the tunnel is used, so the
+	// buffered reader cannot swallow tunnel bytes.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy CONNECT to %s: %w", addr, &HTTPStatusError{StatusCode: resp.StatusCode})
+	}
+	return nil
+}
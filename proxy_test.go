@@ -0,0 +1,75 @@
+package engineclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestProxyRefusesToStartWithoutInboundSecret(t *testing.T) {
+	signer := NewEngineClient("http://localhost:8551", nil, WithTokenSigner(HS256Signer("0123456789abcdef0123456789abcdef")))
+	if _, err := NewProxyServer(signer, nil, nil); err == nil {
+		t.Fatal("proxy started for a TokenSigner client without an inbound secret")
+	}
+	noAuth := NewEngineClient("http://localhost:8551", nil, WithoutAuth())
+	if _, err := NewProxyServer(noAuth, nil, nil); err == nil {
+		t.Fatal("proxy started for an unauthenticated client without an inbound secret")
+	}
+	if _, err := NewProxyServer(signer, []byte("inbound secret"), nil); err != nil {
+		t.Fatalf("proxy with an explicit inbound secret: %v", err)
+	}
+}
+
+func TestProxyRejectsUnauthenticatedCalls(t *testing.T) {
+	upstream := stubEL(t, func(string, []json.RawMessage) (interface{}, *RPCError) {
+		return "0x1", nil
+	})
+	inbound := []byte("inbound secret")
+	client := NewEngineClient(upstream.URL, nil, WithTokenSigner(HS256Signer("0123456789abcdef0123456789abcdef")))
+	proxy, err := NewProxyServer(client, inbound, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(proxy)
+	defer srv.Close()
+
+	valid, err := HS256Signer(inbound).Sign(jwt.MapClaims{"iat": time.Now().Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := HS256Signer("some other secret").Sign(jwt.MapClaims{"iat": time.Now().Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong secret", "Bearer " + forged, http.StatusUnauthorized},
+		{"valid token", "Bearer " + valid, http.StatusOK},
+	}
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader(body))
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// MethodFamily groups the versions of one engine method across forks
+type MethodFamily string
+
+const (
+	FamilyNewPayload              MethodFamily = "newPayload"
+	FamilyForkchoiceUpdated       MethodFamily = "forkchoiceUpdated"
+	FamilyGetPayload              MethodFamily = "getPayload"
+	FamilyGetPayloadBodiesByHash  MethodFamily = "getPayloadBodiesByHash"
+	FamilyGetPayloadBodiesByRange MethodFamily = "getPayloadBodiesByRange"
+)
+
+// MethodArgs carries the values any registered method may need. Each
+// ParamEncoder picks the fields its method takes and ignores the rest.
+type MethodArgs struct {
+	Payload               interface{}
+	VersionedHashes       []Hash
+	ParentBeaconBlockRoot *Hash
+	ExecutionRequests     []string
+	State                 *ForkChoiceState
+	Attributes            *PayloadAttributes
+	PayloadID             string
+	Hashes                []Hash
+	Start, Count          uint64
+	// Extra is passed through untouched for client-specific methods
+	Extra []interface{}
+}
+
+// ParamEncoder builds the JSON-RPC params list for one method version
+type ParamEncoder func(MethodArgs) ([]interface{}, error)
+
+// MethodSpec is the RPC method a family resolves to at a fork
+type MethodSpec struct {
+	Name   string
+	Encode ParamEncoder
+}
+
+// MethodRegistry maps a method family and fork to the RPC method to call.
+// A family resolves to the newest version registered at or before the fork,
+// so forks that leave a method unchanged need no entry of their own.
+type MethodRegistry struct {
+	mu       sync.RWMutex
+	forks    []Fork
+	families []MethodFamily
+	methods  map[MethodFamily]map[Fork]MethodSpec
+}
+
+// NewMethodRegistry returns a registry holding the built-in methods from
+// Paris through Prague
+func NewMethodRegistry() *MethodRegistry {
+	r := &MethodRegistry{
+		forks:   slices.Clone(forkOrder),
+		methods: make(map[MethodFamily]map[Fork]MethodSpec),
+	}
+	for _, m := range builtinMethods {
+		r.Register(m.family, m.fork, m.spec)
+	}
+	return r
+}
+
+// DefaultMethodRegistry is used by clients not given WithMethodRegistry
+var DefaultMethodRegistry = NewMethodRegistry()
+
+// Register adds or replaces the method a family resolves to from fork
+// onwards. A fork the registry has not seen is treated as newer than every
+// known fork.
+func (r *MethodRegistry) Register(family MethodFamily, fork Fork, spec MethodSpec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !slices.Contains(r.forks, fork) {
+		r.forks = append(r.forks, fork)
+	}
+	if r.methods[family] == nil {
+		r.families = append(r.families, family)
+		r.methods[family] = make(map[Fork]MethodSpec)
+	}
+	r.methods[family][fork] = spec
+}
+
+// Resolve returns the method a family uses at fork
+func (r *MethodRegistry) Resolve(family MethodFamily, fork Fork) (MethodSpec, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolve(family, fork)
+}
+
+func (r *MethodRegistry) resolve(family MethodFamily, fork Fork) (MethodSpec, error) {
+	i := slices.Index(r.forks, fork)
+	if i < 0 {
+		return MethodSpec{}, fmt.Errorf("unknown fork %q", fork)
+	}
+	for ; i >= 0; i-- {
+		if spec, ok := r.methods[family][r.forks[i]]; ok {
+			return spec, nil
+		}
+	}
+	return MethodSpec{}, fmt.Errorf("no %s method registered for %s", family, fork)
+}
+
+// Methods returns the method each registered family resolves to at fork, in
+// the order the families were first registered. Families with no version
+// at or before fork are left out.
+func (r *MethodRegistry) Methods(fork Fork) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !slices.Contains(r.forks, fork) {
+		return nil, fmt.Errorf("unknown fork %q", fork)
+	}
+	var methods []string
+	for _, family := range r.families {
+		if spec, err := r.resolve(family, fork); err == nil {
+			methods = append(methods, spec.Name)
+		}
+	}
+	return methods, nil
+}
+
+// familyOf finds the family a method name belongs to and the fork it was
+// registered from
+func (r *MethodRegistry) familyOf(method string) (MethodFamily, Fork, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, family := range r.families {
+		for _, fork := range r.forks {
+			if spec, ok := r.methods[family][fork]; ok && spec.Name == method {
+				return family, fork, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Encode resolves a family at fork and builds its params from args
+func (r *MethodRegistry) Encode(family MethodFamily, fork Fork, args MethodArgs) (string, []interface{}, error) {
+	spec, err := r.Resolve(family, fork)
+	if err != nil {
+		return "", nil, err
+	}
+	params, err := spec.Encode(args)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %v", spec.Name, err)
+	}
+	return spec.Name, params, nil
+}
+
+// CallMethod sends the version of a method family that the client's registry
+// resolves for fork. newPayload and forkchoiceUpdated calls get the same
+// block hash verification, head tracking, status and rewind bookkeeping
+// whichever path sent them.
+func (c *EngineClient) CallMethod(ctx context.Context, family MethodFamily, fork Fork, args MethodArgs) (map[string]interface{}, error) {
+	// The head tracking needs the state whatever a registered encoder
+	// accepts, so a call without one is refused before it is sent.
+	if family == FamilyForkchoiceUpdated && args.State == nil {
+		return nil, fmt.Errorf("%s: missing forkchoice state", family)
+	}
+	method, params, err := c.methods.Encode(family, fork, args)
+	if err != nil {
+		return nil, err
+	}
+	return c.callFamily(ctx, family, fork, method, params, args)
+}
+
+// callFamily sends an already encoded call of a family with the bookkeeping
+// its family needs
+func (c *EngineClient) callFamily(ctx context.Context, family MethodFamily, fork Fork, method string, params interface{}, args MethodArgs) (map[string]interface{}, error) {
+	switch family {
+	case FamilyNewPayload:
+		return c.sendNewPayload(ctx, method, params, fork, args)
+	case FamilyForkchoiceUpdated:
+		return c.sendForkchoiceUpdated(ctx, method, params, fork, args)
+	}
+	return c.makeRequest(ctx, method, params)
+}
+
+// sendNewPayload verifies the payload's block hash when enabled, records its
+// ancestry and status and runs the automatic rewind on an INVALID answer
+func (c *EngineClient) sendNewPayload(ctx context.Context, method string, params interface{}, fork Fork, args MethodArgs) (map[string]interface{}, error) {
+	payload, err := payloadArg(args.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if c.verifyBlockHash {
+		p, err := DecodeExecutionPayload(payload)
+		if err != nil {
+			return nil, err
+		}
+		var requestsHash *Hash
+		if args.ExecutionRequests != nil || forkAtLeast(fork, ForkPrague) {
+			hash, err := RequestsHash(args.ExecutionRequests)
+			if err != nil {
+				return nil, err
+			}
+			requestsHash = &hash
+		}
+		if err := VerifyBlockHash(p, args.ParentBeaconBlockRoot, requestsHash); err != nil {
+			return nil, err
+		}
+	}
+	c.observePayload(payload)
+	response, err := c.makeRequest(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	c.status.recordPayload(payload, response, c.clock.Now())
+	if c.rewindHandler != nil {
+		c.rewindPayload(ctx, payload, response)
+	}
+	return response, nil
+}
+
+// sendForkchoiceUpdated tracks and persists the head the EL accepted, notes
+// the fork of any build it started for GetPayload, and runs the automatic
+// rewind if it rejected the head. If persisting the state fails, the
+// response is returned together with the error.
+func (c *EngineClient) sendForkchoiceUpdated(ctx context.Context, method string, params interface{}, fork Fork, args MethodArgs) (map[string]interface{}, error) {
+	response, err := c.makeRequest(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	if args.Attributes != nil {
+		var result ForkchoiceUpdatedResult
+		if decodeResult(response, &result) == nil && result.PayloadID != nil {
+			c.payloadForks.record(*result.PayloadID, fork)
+		}
+	}
+	state := *args.State
+	err = c.observeForkchoice(state, response)
+	if c.rewindHandler != nil {
+		c.rewindForkchoice(ctx, state, response)
+	}
+	return response, err
+}
+
+// maxPayloadForks bounds how many started builds GetPayload remembers the
+// fork of
+const maxPayloadForks = 64
+
+// payloadForks maps the payload IDs of recent builds to the fork their
+// forkchoiceUpdated was sent at, oldest evicted first
+type payloadForks struct {
+	mu    sync.Mutex
+	forks map[string]Fork
+	order []string
+}
+
+func (p *payloadForks) record(id string, fork Fork) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.forks == nil {
+		p.forks = make(map[string]Fork)
+	}
+	if _, ok := p.forks[id]; !ok {
+		p.order = append(p.order, id)
+	}
+	p.forks[id] = fork
+	if len(p.order) > maxPayloadForks {
+		delete(p.forks, p.order[0])
+		p.order = p.order[1:]
+	}
+}
+
+func (p *payloadForks) lookup(id string) (Fork, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fork, ok := p.forks[id]
+	return fork, ok
+}
+
+// payloadArg returns a newPayload argument in the loosely typed form the
+// observers read, whether it was given as a map or a typed payload
+func payloadArg(payload interface{}) (map[string]interface{}, error) {
+	switch p := payload.(type) {
+	case map[string]interface{}:
+		return p, nil
+	case *ExecutionPayload:
+		return payloadMap(p)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+	return m, nil
+}
+
+var builtinMethods = []struct {
+	family MethodFamily
+	fork   Fork
+	spec   MethodSpec
+}{
+	{FamilyNewPayload, ForkParis, MethodSpec{"engine_newPayloadV1", encodePayload}},
+	{FamilyNewPayload, ForkShanghai, MethodSpec{"engine_newPayloadV2", encodePayload}},
+	{FamilyNewPayload, ForkCancun, MethodSpec{"engine_newPayloadV3", encodePayloadWithBlobs}},
+	{FamilyNewPayload, ForkPrague, MethodSpec{"engine_newPayloadV4", encodePayloadWithRequests}},
+	{FamilyForkchoiceUpdated, ForkParis, MethodSpec{"engine_forkchoiceUpdatedV1", encodeForkchoice}},
+	{FamilyForkchoiceUpdated, ForkShanghai, MethodSpec{"engine_forkchoiceUpdatedV2", encodeForkchoice}},
+	{FamilyForkchoiceUpdated, ForkCancun, MethodSpec{"engine_forkchoiceUpdatedV3", encodeForkchoice}},
+	{FamilyGetPayload, ForkParis, MethodSpec{"engine_getPayloadV1", encodePayloadID}},
+	{FamilyGetPayload, ForkShanghai, MethodSpec{"engine_getPayloadV2", encodePayloadID}},
+	{FamilyGetPayload, ForkCancun, MethodSpec{"engine_getPayloadV3", encodePayloadID}},
+	{FamilyGetPayload, ForkPrague, MethodSpec{"engine_getPayloadV4", encodePayloadID}},
+	{FamilyGetPayloadBodiesByHash, ForkShanghai, MethodSpec{"engine_getPayloadBodiesByHashV1", encodeHashes}},
+	{FamilyGetPayloadBodiesByRange, ForkShanghai, MethodSpec{"engine_getPayloadBodiesByRangeV1", encodeRange}},
+}
+
+func encodePayload(args MethodArgs) ([]interface{}, error) {
+	if args.Payload == nil {
+		return nil, fmt.Errorf("missing payload")
+	}
+	return []interface{}{args.Payload}, nil
+}
+
+func encodePayloadWithBlobs(args MethodArgs) ([]interface{}, error) {
+	params, err := encodePayload(args)
+	if err != nil {
+		return nil, err
+	}
+	if args.ParentBeaconBlockRoot == nil {
+		return nil, fmt.Errorf("missing parent beacon block root")
+	}
+	hashes := args.VersionedHashes
+	if hashes == nil {
+		hashes = []Hash{}
+	}
+	return append(params, hashes, args.ParentBeaconBlockRoot), nil
+}
+
+func encodePayloadWithRequests(args MethodArgs) ([]interface{}, error) {
+	params, err := encodePayloadWithBlobs(args)
+	if err != nil {
+		return nil, err
+	}
+	requests := args.ExecutionRequests
+	if requests == nil {
+		requests = []string{}
+	}
+	return append(params, requests), nil
+}
+
+func encodeForkchoice(args MethodArgs) ([]interface{}, error) {
+	if args.State == nil {
+		return nil, fmt.Errorf("missing forkchoice state")
+	}
+	params := []interface{}{args.State}
+	if args.Attributes != nil {
+		params = append(params, args.Attributes)
+	}
+	return params, nil
+}
+
+func encodePayloadID(args MethodArgs) ([]interface{}, error) {
+	if args.PayloadID == "" {
+		return nil, fmt.Errorf("missing payload id")
+	}
+	return []interface{}{args.PayloadID}, nil
+}
+
+func encodeHashes(args MethodArgs) ([]interface{}, error) {
+	hashes := args.Hashes
+	if hashes == nil {
+		hashes = []Hash{}
+	}
+	return []interface{}{hashes}, nil
+}
+
+func encodeRange(args MethodArgs) ([]interface{}, error) {
+	return []interface{}{fmt.Sprintf("0x%x", args.Start), fmt.Sprintf("0x%x", args.Count)}, nil
+}
@@ -0,0 +1,93 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestForkchoiceUpdatedVersion(t *testing.T) {
+	root := Hash{2}
+	tests := []struct {
+		name       string
+		opts       []Option
+		attributes *PayloadAttributes
+		want       string
+	}{
+		{"no attributes", nil, nil, "engine_forkchoiceUpdatedV1"},
+		{"no attributes on cancun", []Option{WithFork(ForkCancun)}, nil, "engine_forkchoiceUpdatedV3"},
+		{"withdrawals", nil, &PayloadAttributes{Timestamp: time.Unix(1, 0), Withdrawals: []Withdrawal{}}, "engine_forkchoiceUpdatedV2"},
+		{"beacon root", nil, &PayloadAttributes{Timestamp: time.Unix(1, 0), Withdrawals: []Withdrawal{}, ParentBeaconBlockRoot: &root}, "engine_forkchoiceUpdatedV3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := stubEL(t, func(method string, _ []json.RawMessage) (interface{}, *RPCError) {
+				got = method
+				return ForkchoiceUpdatedResult{PayloadStatus: PayloadStatus{Status: StatusValid}}, nil
+			})
+			c := NewEngineClient(srv.URL, nil, append(tt.opts, WithoutAuth())...)
+			if _, err := c.ForkchoiceUpdated(context.Background(), ForkChoiceState{HeadBlockHash: Hash{1}}, tt.attributes); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("sent %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayloadVersions(t *testing.T) {
+	var sent []string
+	srv := stubEL(t, func(method string, _ []json.RawMessage) (interface{}, *RPCError) {
+		sent = append(sent, method)
+		id := "0x0000000000000001"
+		return map[string]interface{}{"payloadStatus": PayloadStatus{Status: StatusValid}, "payloadId": id, "status": StatusValid}, nil
+	})
+	c := NewEngineClient(srv.URL, nil, WithoutAuth())
+	ctx := context.Background()
+
+	if _, err := c.NewPayload(ctx, map[string]interface{}{"blockHash": Hash{1}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NewPayload(ctx, map[string]interface{}{"blockHash": Hash{1}, "withdrawals": []interface{}{}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NewPayload(ctx, map[string]interface{}{"blockHash": Hash{1}, "blobGasUsed": "0x0"}); err == nil {
+		t.Fatal("NewPayload sent a Cancun payload without its blob hashes and beacon root")
+	}
+
+	attributes := &PayloadAttributes{Timestamp: time.Unix(1, 0), Withdrawals: []Withdrawal{}}
+	if _, err := c.ForkchoiceUpdated(ctx, ForkChoiceState{HeadBlockHash: Hash{1}}, attributes); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetPayload(ctx, "0x0000000000000001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetPayload(ctx, "0x0000000000000002"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"engine_newPayloadV1", "engine_newPayloadV2", "engine_forkchoiceUpdatedV2", "engine_getPayloadV2", "engine_getPayloadV1"}
+	if !slices.Equal(sent, want) {
+		t.Fatalf("sent %v, want %v", sent, want)
+	}
+}
+
+func TestCallMethodRequiresForkchoiceState(t *testing.T) {
+	srv := stubEL(t, func(method string, _ []json.RawMessage) (interface{}, *RPCError) {
+		t.Errorf("sent %s without a forkchoice state", method)
+		return ForkchoiceUpdatedResult{PayloadStatus: PayloadStatus{Status: StatusValid}}, nil
+	})
+	registry := NewMethodRegistry()
+	registry.Register(FamilyForkchoiceUpdated, ForkParis, MethodSpec{
+		Name:   "engine_forkchoiceUpdatedV1",
+		Encode: func(MethodArgs) ([]interface{}, error) { return []interface{}{}, nil },
+	})
+	c := NewEngineClient(srv.URL, nil, WithoutAuth(), WithMethodRegistry(registry))
+	if _, err := c.CallMethod(context.Background(), FamilyForkchoiceUpdated, ForkParis, MethodArgs{}); err == nil {
+		t.Fatal("CallMethod accepted a forkchoiceUpdated without a state")
+	}
+}
@@ -0,0 +1,158 @@
+package engineclient
+
+import "sync"
+
+const (
+	headHistorySize = 64
+	knownBlocksSize = 1024
+)
+
+// ReorgEvent describes a forkchoice head change whose new head does not build
+// on the previous head
+type ReorgEvent struct {
+	OldHead Hash
+	NewHead Hash
+	// CommonAncestor is the most recent block shared by both chains, or the
+	// zero hash when it is not among the blocks this client has seen
+	CommonAncestor Hash
+	// Depth is the number of blocks of the old chain that were abandoned.
+	// When the common ancestor is unknown it is estimated from block numbers
+	// and DepthExact is false.
+	Depth      uint64
+	DepthExact bool
+}
+
+type knownBlock struct {
+	parent Hash
+	number uint64
+}
+
+// headTracker remembers recent forkchoice heads and the ancestry of payloads
+// submitted through newPayload
+type headTracker struct {
+	mu     sync.Mutex
+	heads  []Hash
+	blocks map[Hash]knownBlock
+	order  []Hash
+}
+
+func newHeadTracker() *headTracker {
+	return &headTracker{blocks: make(map[Hash]knownBlock)}
+}
+
+// addBlock records a payload's parent and number for later ancestry lookups
+func (t *headTracker) addBlock(hash, parent Hash, number uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.blocks[hash]; ok {
+		return
+	}
+	if len(t.order) == knownBlocksSize {
+		delete(t.blocks, t.order[0])
+		t.order = t.order[1:]
+	}
+	t.blocks[hash] = knownBlock{parent: parent, number: number}
+	t.order = append(t.order, hash)
+}
+
+// setHead records a new head and returns a reorg event if it does not extend
+// the previous one. It returns nil when the new head's ancestry is too
+// unknown to tell.
+func (t *headTracker) setHead(head Hash) *ReorgEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.heads) > 0 && t.heads[len(t.heads)-1] == head {
+		return nil
+	}
+	first := len(t.heads) == 0
+	var prev Hash
+	if !first {
+		prev = t.heads[len(t.heads)-1]
+	}
+	if len(t.heads) == headHistorySize {
+		t.heads = t.heads[1:]
+	}
+	t.heads = append(t.heads, head)
+	if first {
+		return nil
+	}
+
+	block, known := t.blocks[head]
+	if known && block.parent == prev {
+		return nil
+	}
+
+	// Collect the old head's known ancestry with block numbers, then walk
+	// back from the new head until the chains meet.
+	oldBlock, oldKnown := t.blocks[prev]
+	oldChain := map[Hash]uint64{prev: oldBlock.number}
+	for hash := prev; ; {
+		b, ok := t.blocks[hash]
+		if !ok || b.number == 0 {
+			break
+		}
+		oldChain[b.parent] = b.number - 1
+		hash = b.parent
+	}
+	for hash := head; ; {
+		if number, ok := oldChain[hash]; ok {
+			if hash == prev {
+				// The new head descends from the old one; several blocks
+				// were simply applied at once.
+				return nil
+			}
+			return &ReorgEvent{
+				OldHead:        prev,
+				NewHead:        head,
+				CommonAncestor: hash,
+				Depth:          oldBlock.number - number,
+				DepthExact:     true,
+			}
+		}
+		b, ok := t.blocks[hash]
+		if !ok {
+			break
+		}
+		hash = b.parent
+	}
+
+	// The chains did not meet among the known blocks. A new head no higher
+	// than the old one cannot descend from it, so that is still a reorg of
+	// estimated depth; otherwise the head may simply have advanced past
+	// blocks this client never saw.
+	if known && oldKnown && block.number <= oldBlock.number {
+		return &ReorgEvent{OldHead: prev, NewHead: head, Depth: oldBlock.number - block.number + 1}
+	}
+	return nil
+}
+
+// history returns the recorded heads, oldest first
+func (t *headTracker) history() []Hash {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Hash(nil), t.heads...)
+}
+
+// HeadHistory returns the most recent heads applied through forkchoiceUpdated,
+// oldest first
+func (c *EngineClient) HeadHistory() []Hash {
+	return c.heads.history()
+}
+
+// observePayload records the ancestry of a submitted payload when reorg
+// detection or automatic rewinds are enabled
+func (c *EngineClient) observePayload(payload map[string]interface{}) {
+	if c.reorgHandler == nil && c.rewindHandler == nil {
+		return
+	}
+	p, err := DecodeExecutionPayload(payload)
+	if err != nil {
+		return
+	}
+	number, err := decodeQuantity(p.BlockNumber)
+	if err != nil {
+		return
+	}
+	c.heads.addBlock(p.BlockHash, p.ParentHash, number)
+}
@@ -0,0 +1,63 @@
+package engineclient
+
+import "testing"
+
+func TestSetHead(t *testing.T) {
+	a, b, c, d, e := Hash{0xa}, Hash{0xb}, Hash{0xc}, Hash{0xd}, Hash{0xe}
+	tests := []struct {
+		name   string
+		blocks map[Hash]knownBlock
+		heads  []Hash
+		want   *ReorgEvent
+	}{
+		{
+			name:  "linear advance with unknown ancestry",
+			heads: []Hash{a, b, c},
+		},
+		{
+			name:   "linear advance",
+			blocks: map[Hash]knownBlock{a: {Hash{}, 1}, b: {a, 2}, c: {b, 3}},
+			heads:  []Hash{a, b, c},
+		},
+		{
+			name:   "several blocks at once",
+			blocks: map[Hash]knownBlock{a: {Hash{}, 1}, b: {a, 2}, c: {b, 3}},
+			heads:  []Hash{a, c},
+		},
+		{
+			name:   "advance past unseen blocks",
+			blocks: map[Hash]knownBlock{a: {Hash{}, 1}, c: {b, 3}},
+			heads:  []Hash{a, c},
+		},
+		{
+			name:   "reorg",
+			blocks: map[Hash]knownBlock{a: {Hash{}, 1}, b: {a, 2}, c: {b, 3}, d: {a, 2}, e: {d, 3}},
+			heads:  []Hash{c, e},
+			want:   &ReorgEvent{OldHead: c, NewHead: e, CommonAncestor: a, Depth: 2, DepthExact: true},
+		},
+		{
+			name:   "reorg to an unrelated lower head",
+			blocks: map[Hash]knownBlock{c: {b, 3}, e: {d, 3}},
+			heads:  []Hash{c, e},
+			want:   &ReorgEvent{OldHead: c, NewHead: e, Depth: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newHeadTracker()
+			for hash, block := range tt.blocks {
+				tracker.addBlock(hash, block.parent, block.number)
+			}
+			var got *ReorgEvent
+			for i, head := range tt.heads {
+				if got != nil {
+					t.Fatalf("head %d: unexpected %+v", i-1, got)
+				}
+				got = tracker.setHead(head)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
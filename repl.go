@@ -0,0 +1,186 @@
+package engineclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const replHelp = `Enter a method followed by optional JSON params, for example:
+  engine_exchangeCapabilities ["engine_newPayloadV1"]
+  engine_getPayloadV2 "0x0000000000000001"
+A single non-array value is sent as the only parameter.
+
+Commands:
+  history    list previous entries
+  !N         re-run entry N from history
+  !!         re-run the previous entry
+  help       show this message
+  exit       leave the session`
+
+// runRepl opens an interactive session that sends typed methods to the EL
+func runRepl(args []string) error {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	historyPath := fs.String("history", defaultHistoryPath(), "file to persist history to (empty to disable)")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout for each call")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	history := loadHistory(*historyPath)
+	fmt.Printf("Connected to %s. Type \"help\" for usage.\n", cfg.Endpoint)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for {
+		fmt.Print("engine> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			continue
+		case line == "exit" || line == "quit":
+			return nil
+		case line == "help":
+			fmt.Println(replHelp)
+			continue
+		case line == "history":
+			for i, entry := range history {
+				fmt.Printf("%4d  %s\n", i+1, entry)
+			}
+			continue
+		case strings.HasPrefix(line, "!"):
+			entry, err := recallHistory(history, line)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println(entry)
+			line = entry
+		}
+
+		history = append(history, line)
+		appendHistory(*historyPath, line)
+
+		method, params, err := parseReplLine(line)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		start := time.Now()
+		result, err := client.Call(ctx, method, params)
+		cancel()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		out.emit(record{{"method", method}, {"result", result}, {"error", err}, {"duration", elapsed}}, func(w io.Writer) {
+			if err != nil {
+				fmt.Fprintf(w, "Error: %v\n", err)
+				return
+			}
+			pretty, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Fprintf(w, "%s\n(%s)\n", pretty, elapsed)
+		})
+	}
+}
+
+// parseReplLine splits a line into the method name and its JSON params
+func parseReplLine(line string) (string, []interface{}, error) {
+	method, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return method, []interface{}{}, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(rest), &value); err != nil {
+		return "", nil, fmt.Errorf("invalid JSON params: %v", err)
+	}
+	if params, ok := value.([]interface{}); ok {
+		return method, params, nil
+	}
+	return method, []interface{}{value}, nil
+}
+
+func recallHistory(history []string, line string) (string, error) {
+	if line == "!!" {
+		if len(history) == 0 {
+			return "", fmt.Errorf("history is empty")
+		}
+		return history[len(history)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(history) {
+		return "", fmt.Errorf("no history entry %s", line[1:])
+	}
+	return history[n-1], nil
+}
+
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".engine_client_history")
+}
+
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	return readLines(f)
+}
+
+func readLines(r io.Reader) []string {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func appendHistory(path, line string) {
+	if path == "" {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
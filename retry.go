@@ -0,0 +1,132 @@
+package engineclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how a failed call is retried. Transport failures,
+// timeouts and the RetryStatuses replies are retried; RPC errors, invalid
+// responses and other statuses are returned immediately.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int
+	// InitialBackoff is doubled after every attempt, up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryStatuses are the HTTP statuses worth retrying; nil means
+	// DefaultRetryStatuses
+	RetryStatuses []int
+}
+
+// DefaultRetryStatuses are the replies of an EL, or a proxy in front of it,
+// that is overloaded or restarting
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultRetryPolicy retries up to three times over roughly two seconds
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// retryDelay reports whether err should be retried and how long to wait
+// first. A Retry-After sent with a retried status overrides the backoff.
+func (p *RetryPolicy) retryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	if errors.Is(err, context.Canceled) {
+		return 0, false
+	}
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		if !transportFailure(err) {
+			return 0, false
+		}
+		return backoff, true
+	}
+	statuses := p.RetryStatuses
+	if statuses == nil {
+		statuses = DefaultRetryStatuses
+	}
+	if !slices.Contains(statuses, statusErr.StatusCode) {
+		return 0, false
+	}
+	if statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter, true
+	}
+	return backoff, true
+}
+
+// transportFailure reports whether err means the call may not have reached
+// the EL, or its answer was lost on the way back: a failed dial, read or
+// write, a connection reset, refused or closed early, or a timeout
+func transportFailure(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, ErrTimeout)
+}
+
+// parseRetryAfter decodes a Retry-After header given either as seconds or as
+// an HTTP date, returning zero when it is absent or malformed
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// withRetries runs send under the client's retry policy, waiting between
+// attempts. A wait that would outlast the context deadline is not started;
+// the last error is returned instead.
+func (c *EngineClient) withRetries(ctx context.Context, call requestInfo, send func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	if c.retryPolicy == nil || c.retryPolicy.MaxAttempts <= 1 {
+		return send()
+	}
+	backoff := c.retryPolicy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		result, err := send()
+		if err == nil || attempt >= c.retryPolicy.MaxAttempts {
+			return result, err
+		}
+		delay, ok := c.retryPolicy.retryDelay(err, backoff)
+		if !ok {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && deadline.Sub(c.clock.Now()) < delay {
+			return nil, err
+		}
+		c.logger.Info("retrying engine call", append(call.logAttrs(), "attempt", attempt, "delay", delay, "err", err)...)
+
+		if !c.sleep(ctx, delay) {
+			return nil, err
+		}
+		backoff *= 2
+		if backoff > c.retryPolicy.MaxBackoff {
+			backoff = c.retryPolicy.MaxBackoff
+		}
+	}
+}
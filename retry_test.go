@@ -0,0 +1,45 @@
+package engineclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	const backoff = time.Second
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		err    error
+		want   time.Duration
+		retry  bool
+	}{
+		{"connection refused", RetryPolicy{}, fmt.Errorf("failed to make request: %w", dialErr), backoff, true},
+		{"connection reset", RetryPolicy{}, fmt.Errorf("read: %w", syscall.ECONNRESET), backoff, true},
+		{"timeout", RetryPolicy{}, fmt.Errorf("failed to make request: %w", wrapTimeout(context.DeadlineExceeded)), backoff, true},
+		{"canceled", RetryPolicy{}, context.Canceled, 0, false},
+		{"rpc error", RetryPolicy{}, &RPCError{Code: codeUnknownPayload}, 0, false},
+		{"invalid response", RetryPolicy{}, errors.New("failed to decode response: unexpected character"), 0, false},
+		{"id mismatch", RetryPolicy{}, errors.New("response id 2 does not match request id 1"), 0, false},
+		{"503", RetryPolicy{}, &HTTPStatusError{StatusCode: http.StatusServiceUnavailable}, backoff, true},
+		{"429 with retry-after", RetryPolicy{}, &HTTPStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Second}, 5 * time.Second, true},
+		{"500", RetryPolicy{}, &HTTPStatusError{StatusCode: http.StatusInternalServerError}, 0, false},
+		{"configured 500", RetryPolicy{RetryStatuses: []int{http.StatusInternalServerError}}, &HTTPStatusError{StatusCode: http.StatusInternalServerError}, backoff, true},
+		{"503 not configured", RetryPolicy{RetryStatuses: []int{http.StatusInternalServerError}}, &HTTPStatusError{StatusCode: http.StatusServiceUnavailable}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := tt.policy.retryDelay(tt.err, backoff)
+			if delay != tt.want || retry != tt.retry {
+				t.Fatalf("got (%v, %v), want (%v, %v)", delay, retry, tt.want, tt.retry)
+			}
+		})
+	}
+}
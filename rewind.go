@@ -0,0 +1,142 @@
+package engineclient
+
+import (
+	"context"
+	"fmt"
+)
+
+// RewindEvent describes a corrective forkchoiceUpdated sent after the EL
+// reported a block INVALID, pointing the head back at the block's latest
+// valid ancestor
+type RewindEvent struct {
+	InvalidBlock    Hash
+	LatestValidHash Hash
+	// Invalidated lists the rejected block and the ancestors between it and
+	// LatestValidHash that this client has seen, newest first. Complete is
+	// false when the walk back did not reach LatestValidHash, so the range
+	// may extend further.
+	Invalidated []Hash
+	Complete    bool
+	// FromNumber and ToNumber bound the invalidated range; both are zero
+	// when the rejected block was never submitted through this client
+	FromNumber uint64
+	ToNumber   uint64
+	// State is the forkchoice state sent and Status the EL's answer to it
+	State  ForkChoiceState
+	Status *PayloadStatus
+}
+
+// invalidated walks back from an invalid block to its latest valid
+// ancestor, returning the blocks in between, newest first, and their
+// number range
+func (t *headTracker) invalidated(invalid, latestValid Hash) (hashes []Hash, from, to uint64, complete bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hashes = []Hash{invalid}
+	b, ok := t.blocks[invalid]
+	if !ok {
+		return hashes, 0, 0, false
+	}
+	to = b.number
+	for b.parent != latestValid {
+		parent, ok := t.blocks[b.parent]
+		if !ok {
+			return hashes, to - uint64(len(hashes)) + 1, to, false
+		}
+		hashes = append(hashes, b.parent)
+		b = parent
+	}
+	return hashes, to - uint64(len(hashes)) + 1, to, true
+}
+
+// RewindToLatestValid points the EL back at the latest valid ancestor named
+// in an INVALID status for block invalid. The safe and finalized hashes of
+// current are kept unless they are among the invalidated blocks, in which
+// case they fall back to the latest valid hash too. It returns nil without
+// calling the EL when the status is not INVALID or carries no non-zero
+// latestValidHash, as then there is nothing known to be valid to return to.
+func (c *EngineClient) RewindToLatestValid(ctx context.Context, invalid Hash, status PayloadStatus, current ForkChoiceState) (*RewindEvent, error) {
+	if status.Status != StatusInvalid || status.LatestValidHash == nil || *status.LatestValidHash == (Hash{}) {
+		return nil, nil
+	}
+	valid := *status.LatestValidHash
+	event := &RewindEvent{InvalidBlock: invalid, LatestValidHash: valid}
+	event.Invalidated, event.FromNumber, event.ToNumber, event.Complete = c.heads.invalidated(invalid, valid)
+
+	event.State = ForkChoiceState{HeadBlockHash: valid, SafeBlockHash: current.SafeBlockHash, FinalizedBlockHash: current.FinalizedBlockHash}
+	for _, hash := range event.Invalidated {
+		if event.State.SafeBlockHash == hash {
+			event.State.SafeBlockHash = valid
+		}
+		if event.State.FinalizedBlockHash == hash {
+			event.State.FinalizedBlockHash = valid
+		}
+	}
+
+	// The call skips CallMethod's own rewind so a rejected rewind cannot
+	// trigger another one.
+	method, params, err := c.methods.Encode(FamilyForkchoiceUpdated, c.fork, MethodArgs{State: &event.State})
+	if err != nil {
+		return event, fmt.Errorf("failed to rewind to %s: %v", valid, err)
+	}
+	response, err := c.makeRequest(ctx, method, params)
+	if err != nil {
+		return event, fmt.Errorf("failed to rewind to %s: %v", valid, err)
+	}
+	if err := c.observeForkchoice(event.State, response); err != nil {
+		return event, err
+	}
+	var result ForkchoiceUpdatedResult
+	if err := decodeResult(response, &result); err != nil {
+		return event, fmt.Errorf("failed to rewind to %s: %v", valid, err)
+	}
+	event.Status = &result.PayloadStatus
+	c.logger.Warn("rewound to latest valid block",
+		"invalid", invalid, "latestValidHash", valid, "invalidated", len(event.Invalidated), "status", result.PayloadStatus.Status)
+	return event, nil
+}
+
+// rewindForkchoice runs the automatic rewind after a forkchoiceUpdated to
+// state, whose head the EL may have rejected
+func (c *EngineClient) rewindForkchoice(ctx context.Context, state ForkChoiceState, response map[string]interface{}) {
+	var result ForkchoiceUpdatedResult
+	if decodeResult(response, &result) != nil {
+		return
+	}
+	c.rewind(ctx, state.HeadBlockHash, result.PayloadStatus, state)
+}
+
+// rewindPayload runs the automatic rewind after a newPayload, but only when
+// the INVALID status also invalidates the head last applied, since otherwise
+// the EL is not pointing at a bad block
+func (c *EngineClient) rewindPayload(ctx context.Context, payload map[string]interface{}, response map[string]interface{}) {
+	var status PayloadStatus
+	if decodeResult(response, &status) != nil || status.Status != StatusInvalid || status.LatestValidHash == nil {
+		return
+	}
+	invalid, err := HexToHash(stringField(payload, "blockHash"))
+	if err != nil {
+		return
+	}
+	current, ok := c.status.appliedForkchoice()
+	if !ok {
+		return
+	}
+	hashes, _, _, _ := c.heads.invalidated(invalid, *status.LatestValidHash)
+	for _, hash := range hashes {
+		if hash == current.HeadBlockHash {
+			c.rewind(ctx, invalid, status, current)
+			return
+		}
+	}
+}
+
+func (c *EngineClient) rewind(ctx context.Context, invalid Hash, status PayloadStatus, current ForkChoiceState) {
+	event, err := c.RewindToLatestValid(ctx, invalid, status, current)
+	if err != nil {
+		c.logger.Error("automatic rewind failed", "invalid", invalid, "err", err)
+	}
+	if event != nil {
+		c.rewindHandler(*event, err)
+	}
+}
@@ -0,0 +1,186 @@
+package engineclient
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// rlpBytes encodes a byte string
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+// rlpList encodes a list whose items are already RLP encoded
+func rlpList(items ...[]byte) []byte {
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+	out := rlpHeader(0xc0, size)
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// rlpUint encodes an unsigned integer with no leading zero bytes
+func rlpUint(v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	i := 0
+	for i < len(buf) && buf[i] == 0 {
+		i++
+	}
+	return rlpBytes(buf[i:])
+}
+
+// rlpBigInt encodes a non-negative big integer
+func rlpBigInt(v *big.Int) []byte {
+	return rlpBytes(v.Bytes())
+}
+
+func rlpHeader(offset byte, size int) []byte {
+	if size < 56 {
+		return []byte{offset + byte(size)}
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(size))
+	i := 0
+	for buf[i] == 0 {
+		i++
+	}
+	return append([]byte{offset + 55 + byte(8-i)}, buf[i:]...)
+}
+
+// rlpSplit splits the first item off b, returning whether it is a list, its
+// content and the bytes that follow it
+func rlpSplit(b []byte) (bool, []byte, []byte, error) {
+	if len(b) == 0 {
+		return false, nil, nil, fmt.Errorf("rlp: unexpected end of input")
+	}
+	prefix := b[0]
+	var list bool
+	var offset, size int
+	switch {
+	case prefix < 0x80:
+		return false, b[:1], b[1:], nil
+	case prefix < 0xb8:
+		offset, size = 1, int(prefix-0x80)
+	case prefix < 0xc0:
+		n := int(prefix - 0xb7)
+		v, err := rlpLength(b[1:], n)
+		if err != nil {
+			return false, nil, nil, err
+		}
+		offset, size = 1+n, v
+	case prefix < 0xf8:
+		list, offset, size = true, 1, int(prefix-0xc0)
+	default:
+		n := int(prefix - 0xf7)
+		v, err := rlpLength(b[1:], n)
+		if err != nil {
+			return false, nil, nil, err
+		}
+		list, offset, size = true, 1+n, v
+	}
+	if size < 0 || len(b)-offset < size {
+		return false, nil, nil, fmt.Errorf("rlp: item of %d bytes exceeds input", size)
+	}
+	return list, b[offset : offset+size], b[offset+size:], nil
+}
+
+func rlpLength(b []byte, n int) (int, error) {
+	if n > 8 || len(b) < n {
+		return 0, fmt.Errorf("rlp: invalid length prefix")
+	}
+	var v uint64
+	for _, c := range b[:n] {
+		v = v<<8 | uint64(c)
+	}
+	if v > 1<<40 {
+		return 0, fmt.Errorf("rlp: item of %d bytes is too large", v)
+	}
+	return int(v), nil
+}
+
+// rlpItems splits the content of a list into the full encodings of its items
+func rlpItems(content []byte) ([][]byte, error) {
+	var items [][]byte
+	for len(content) > 0 {
+		_, _, rest, err := rlpSplit(content)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, content[:len(content)-len(rest)])
+		content = rest
+	}
+	return items, nil
+}
+
+// rlpString returns the content of an encoded byte string
+func rlpString(item []byte) ([]byte, error) {
+	list, content, _, err := rlpSplit(item)
+	if err != nil {
+		return nil, err
+	}
+	if list {
+		return nil, fmt.Errorf("rlp: expected string, got list")
+	}
+	return content, nil
+}
+
+// rlpListContent returns the items of an encoded list
+func rlpListContent(item []byte) ([][]byte, error) {
+	list, content, _, err := rlpSplit(item)
+	if err != nil {
+		return nil, err
+	}
+	if !list {
+		return nil, fmt.Errorf("rlp: expected list, got string")
+	}
+	return rlpItems(content)
+}
+
+// readRLPItem reads one complete top-level item from a stream of
+// concatenated items, returning io.EOF once the stream is exhausted
+func readRLPItem(r *bufio.Reader) ([]byte, error) {
+	prefix, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	headerLen := 1
+	switch p := prefix[0]; {
+	case p >= 0xb8 && p < 0xc0:
+		headerLen += int(p - 0xb7)
+	case p >= 0xf8:
+		headerLen += int(p - 0xf7)
+	}
+	header, err := r.Peek(headerLen)
+	if err != nil {
+		return nil, fmt.Errorf("rlp: truncated item header: %v", err)
+	}
+	var size int
+	switch p := header[0]; {
+	case p < 0x80:
+		size = 0
+	case p < 0xb8:
+		size = int(p - 0x80)
+	case p < 0xc0, p >= 0xf8:
+		if size, err = rlpLength(header[1:], headerLen-1); err != nil {
+			return nil, err
+		}
+	default:
+		size = int(p - 0xc0)
+	}
+	item := make([]byte, headerLen+size)
+	if _, err := io.ReadFull(r, item); err != nil {
+		return nil, fmt.Errorf("rlp: truncated item: %v", err)
+	}
+	return item, nil
+}
@@ -0,0 +1,570 @@
+package engineclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scenario is a scripted sequence of engine calls with expected outcomes,
+// run against an EL under test the way a consensus client would drive it
+type Scenario struct {
+	Name string `json:"name"`
+	// Fork is the default for steps that name a method family
+	Fork  Fork           `json:"fork"`
+	Steps []ScenarioStep `json:"steps"`
+}
+
+// ScenarioStep is one call. Method is either a method family such as
+// newPayload, resolved to the version for the step's fork and built from
+// Args, or a full RPC method name sent with Params as given.
+//
+// Any string in Args or Params of the form "${step.path}" is replaced by
+// the value at path in the result of the named earlier step, such as
+// "${build.executionPayload}" or "${fcu.payloadId}".
+type ScenarioStep struct {
+	Name   string          `json:"name"`
+	Method string          `json:"method"`
+	Fork   Fork            `json:"fork"`
+	Args   json.RawMessage `json:"args"`
+	Params json.RawMessage `json:"params"`
+	// Wait is a duration to pause before the call, such as the time an EL
+	// is given to build a payload
+	Wait   string         `json:"wait"`
+	Expect ScenarioExpect `json:"expect"`
+}
+
+// ScenarioExpect is what a step must return to pass. With no expectations a
+// step passes when the call succeeds.
+type ScenarioExpect struct {
+	// Status lists the acceptable payload statuses, read from the result or
+	// its payloadStatus
+	Status statusSet `json:"status"`
+	// Error is the JSON-RPC error code the call must fail with
+	Error *int `json:"error"`
+	// PayloadID requires a payloadId to be present or absent
+	PayloadID *bool `json:"payloadId"`
+	// LatestValidHash is the latestValidHash the status must carry; "null"
+	// requires it to be null
+	LatestValidHash string `json:"latestValidHash"`
+}
+
+// statusSet accepts a single status or a list of them
+type statusSet []string
+
+func (s *statusSet) UnmarshalJSON(data []byte) error {
+	var one string
+	if json.Unmarshal(data, &one) == nil {
+		*s = statusSet{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("status must be a string or a list of strings")
+	}
+	*s = many
+	return nil
+}
+
+// scenarioArgs are the named arguments of a method family step
+type scenarioArgs struct {
+	Payload               interface{}        `json:"payload"`
+	VersionedHashes       []Hash             `json:"versionedHashes"`
+	ParentBeaconBlockRoot *Hash              `json:"parentBeaconBlockRoot"`
+	ExecutionRequests     []string           `json:"executionRequests"`
+	State                 *ForkChoiceState   `json:"state"`
+	Attributes            *PayloadAttributes `json:"attributes"`
+	PayloadID             string             `json:"payloadId"`
+	Hashes                []Hash             `json:"hashes"`
+	Start                 uint64             `json:"start"`
+	Count                 uint64             `json:"count"`
+}
+
+// StepResult is the outcome of one scenario step
+type StepResult struct {
+	Name     string        `json:"name"`
+	Method   string        `json:"method"`
+	Status   string        `json:"status,omitempty"`
+	Pass     bool          `json:"pass"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Duration time.Duration `json:"durationNs"`
+	// Detail says why the step failed or was skipped
+	Detail string `json:"detail,omitempty"`
+}
+
+// ScenarioReport summarizes a scenario run
+type ScenarioReport struct {
+	Name    string       `json:"name"`
+	Passed  int          `json:"passed"`
+	Failed  int          `json:"failed"`
+	Skipped int          `json:"skipped"`
+	Steps   []StepResult `json:"steps"`
+}
+
+// LoadScenario reads a scenario from a JSON file, rejecting unknown fields so
+// a misspelt expectation cannot silently pass
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var s Scenario
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &s, nil
+}
+
+func (s *Scenario) validate() error {
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("scenario has no steps")
+	}
+	seen := make(map[string]bool)
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step %d", i+1)
+		}
+		if seen[step.Name] {
+			return fmt.Errorf("duplicate step name %q", step.Name)
+		}
+		seen[step.Name] = true
+		if step.Method == "" {
+			return fmt.Errorf("%s: missing method", step.Name)
+		}
+		if step.Fork == "" {
+			step.Fork = s.Fork
+		}
+		if step.Wait != "" {
+			if _, err := time.ParseDuration(step.Wait); err != nil {
+				return fmt.Errorf("%s: invalid wait: %v", step.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RunScenario executes every step in order, calling onStep with each result.
+// Once a step fails the rest are skipped, since later steps usually build on
+// earlier ones, unless keepGoing is set.
+func (c *EngineClient) RunScenario(ctx context.Context, s *Scenario, keepGoing bool, onStep func(StepResult)) *ScenarioReport {
+	report := &ScenarioReport{Name: s.Name}
+	results := make(map[string]interface{})
+	failed := false
+	for _, step := range s.Steps {
+		var result StepResult
+		if failed && !keepGoing {
+			result = StepResult{Name: step.Name, Method: step.Method, Skipped: true, Detail: "an earlier step failed"}
+			report.Skipped++
+		} else {
+			var value interface{}
+			result, value = c.runStep(ctx, step, results)
+			results[step.Name] = value
+			if result.Pass {
+				report.Passed++
+			} else {
+				report.Failed++
+				failed = true
+			}
+		}
+		report.Steps = append(report.Steps, result)
+		if onStep != nil {
+			onStep(result)
+		}
+	}
+	return report
+}
+
+// runStep sends one step and checks its expectations, returning the result
+// member of the response for later steps to reference
+func (c *EngineClient) runStep(ctx context.Context, step ScenarioStep, results map[string]interface{}) (StepResult, interface{}) {
+	result := StepResult{Name: step.Name, Method: step.Method}
+	fail := func(format string, args ...interface{}) (StepResult, interface{}) {
+		result.Detail = fmt.Sprintf(format, args...)
+		return result, nil
+	}
+
+	method, params, err := c.stepCall(step, results)
+	if err != nil {
+		return fail("%v", err)
+	}
+	result.Method = method
+	if step.Wait != "" {
+		wait, _ := time.ParseDuration(step.Wait)
+		if !c.sleep(ctx, wait) {
+			return fail("%v", ctx.Err())
+		}
+	}
+
+	start := c.clock.Now()
+	response, err := c.sendStep(ctx, method, params)
+	result.Duration = c.clock.Now().Sub(start)
+	var value interface{}
+	if err == nil {
+		err = decodeResult(response, &value)
+	}
+
+	want := step.Expect
+	var rpcErr *RPCError
+	switch {
+	case want.Error != nil && errors.As(err, &rpcErr):
+		if rpcErr.Code != *want.Error {
+			return fail("got error %d (%s), want %d", rpcErr.Code, rpcErr.Message, *want.Error)
+		}
+		result.Pass = true
+		return result, nil
+	case want.Error != nil && err == nil:
+		return fail("call succeeded, want error %d", *want.Error)
+	case err != nil:
+		return fail("%v", err)
+	}
+
+	status, _ := payloadStatusOf(value)
+	if status != nil {
+		result.Status = status.Status
+	}
+	if len(want.Status) > 0 {
+		if status == nil {
+			return fail("result has no payload status, want %s", strings.Join(want.Status, " or "))
+		}
+		if !slices.Contains(want.Status, status.Status) {
+			detail := fmt.Sprintf("status %s, want %s", status.Status, strings.Join(want.Status, " or "))
+			if status.ValidationError != nil {
+				detail += ": " + *status.ValidationError
+			}
+			return fail("%s", detail)
+		}
+	}
+	if want.LatestValidHash != "" {
+		got := "null"
+		if status != nil && status.LatestValidHash != nil {
+			got = status.LatestValidHash.Hex()
+		}
+		if !strings.EqualFold(got, want.LatestValidHash) {
+			return fail("latestValidHash %s, want %s", got, want.LatestValidHash)
+		}
+	}
+	if want.PayloadID != nil {
+		fields, _ := value.(map[string]interface{})
+		if has := fields["payloadId"] != nil; has != *want.PayloadID {
+			return fail("payloadId present is %t, want %t", has, *want.PayloadID)
+		}
+	}
+	result.Pass = true
+	return result, value
+}
+
+// sendStep sends a step's call. Calls of a registered method, whether the
+// step named its family or the raw method, get the same bookkeeping as
+// CallMethod, for the fork that method version belongs to.
+func (c *EngineClient) sendStep(ctx context.Context, method string, params interface{}) (map[string]interface{}, error) {
+	family, fork, ok := c.methods.familyOf(method)
+	if !ok {
+		return c.makeRequest(ctx, method, params)
+	}
+	args, ok := argsFromParams(family, params)
+	if !ok {
+		// Params that do not fit the method are sent as they are, for
+		// steps that check how the EL rejects them.
+		return c.makeRequest(ctx, method, params)
+	}
+	return c.callFamily(ctx, family, fork, method, params, args)
+}
+
+// argsFromParams reads back the arguments the bookkeeping of a newPayload or
+// forkchoiceUpdated call needs from its positional params
+func argsFromParams(family MethodFamily, params interface{}) (MethodArgs, bool) {
+	var args MethodArgs
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return args, false
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil || len(list) == 0 {
+		return args, false
+	}
+	switch family {
+	case FamilyNewPayload:
+		var payload map[string]interface{}
+		if json.Unmarshal(list[0], &payload) != nil || payload == nil {
+			return args, false
+		}
+		args.Payload = payload
+		targets := []interface{}{&args.VersionedHashes, &args.ParentBeaconBlockRoot, &args.ExecutionRequests}
+		for i, target := range targets {
+			if i+1 < len(list) && json.Unmarshal(list[i+1], target) != nil {
+				return args, false
+			}
+		}
+	case FamilyForkchoiceUpdated:
+		var state ForkChoiceState
+		if json.Unmarshal(list[0], &state) != nil {
+			return args, false
+		}
+		args.State = &state
+		if len(list) > 1 && string(list[1]) != "null" {
+			// Only whether attributes were sent matters once encoded.
+			args.Attributes = &PayloadAttributes{}
+		}
+	}
+	return args, true
+}
+
+// stepCall resolves a step's RPC method and builds its params
+func (c *EngineClient) stepCall(step ScenarioStep, results map[string]interface{}) (string, interface{}, error) {
+	family := MethodFamily(step.Method)
+	if !c.isFamily(family) {
+		raw := step.Params
+		if len(raw) == 0 {
+			raw = json.RawMessage("[]")
+		}
+		params, err := substitute(raw, results)
+		if err != nil {
+			return "", nil, err
+		}
+		return step.Method, params, nil
+	}
+
+	if step.Fork == "" {
+		return "", nil, fmt.Errorf("%s needs a fork", step.Method)
+	}
+	var args scenarioArgs
+	if len(step.Args) > 0 {
+		value, err := substitute(step.Args, results)
+		if err != nil {
+			return "", nil, err
+		}
+		raw, _ := json.Marshal(value)
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return "", nil, fmt.Errorf("invalid args: %v", err)
+		}
+	}
+	method, params, err := c.methods.Encode(family, step.Fork, MethodArgs{
+		Payload:               args.Payload,
+		VersionedHashes:       args.VersionedHashes,
+		ParentBeaconBlockRoot: args.ParentBeaconBlockRoot,
+		ExecutionRequests:     args.ExecutionRequests,
+		State:                 args.State,
+		Attributes:            args.Attributes,
+		PayloadID:             args.PayloadID,
+		Hashes:                args.Hashes,
+		Start:                 args.Start,
+		Count:                 args.Count,
+	})
+	return method, params, err
+}
+
+// isFamily reports whether any fork has a method registered for family
+func (c *EngineClient) isFamily(family MethodFamily) bool {
+	c.methods.mu.RLock()
+	defer c.methods.mu.RUnlock()
+	return len(c.methods.methods[family]) > 0
+}
+
+// payloadStatusOf reads the payload status of a newPayload result or the
+// payloadStatus of a forkchoiceUpdated result
+func payloadStatusOf(value interface{}) (*PayloadStatus, bool) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if nested, ok := fields["payloadStatus"].(map[string]interface{}); ok {
+		fields = nested
+	}
+	if _, ok := fields["status"].(string); !ok {
+		return nil, false
+	}
+	raw, _ := json.Marshal(fields)
+	var status PayloadStatus
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return nil, false
+	}
+	return &status, true
+}
+
+var referencePattern = regexp.MustCompile(`^\$\{([^}]+)\}$`)
+
+// substitute decodes raw and replaces every "${step.path}" string with the
+// referenced value from an earlier step's result
+func substitute(raw json.RawMessage, results map[string]interface{}) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	var walk func(v interface{}) (interface{}, error)
+	walk = func(v interface{}) (interface{}, error) {
+		switch x := v.(type) {
+		case string:
+			m := referencePattern.FindStringSubmatch(x)
+			if m == nil {
+				return x, nil
+			}
+			return lookupReference(m[1], results)
+		case map[string]interface{}:
+			for k, item := range x {
+				replaced, err := walk(item)
+				if err != nil {
+					return nil, err
+				}
+				x[k] = replaced
+			}
+		case []interface{}:
+			for i, item := range x {
+				replaced, err := walk(item)
+				if err != nil {
+					return nil, err
+				}
+				x[i] = replaced
+			}
+		}
+		return v, nil
+	}
+	return walk(value)
+}
+
+// lookupReference resolves "step.path.to.field", where numeric path elements
+// index lists. Step names may contain dots as long as they are unambiguous.
+func lookupReference(ref string, results map[string]interface{}) (interface{}, error) {
+	parts := strings.Split(ref, ".")
+	for n := len(parts); n > 0; n-- {
+		value, ok := results[strings.Join(parts[:n], ".")]
+		if !ok {
+			continue
+		}
+		path := parts[n:]
+		for i, key := range path {
+			switch x := value.(type) {
+			case map[string]interface{}:
+				if value, ok = x[key]; !ok {
+					return nil, fmt.Errorf("${%s}: no field %q", ref, strings.Join(path[:i+1], "."))
+				}
+			case []interface{}:
+				index, err := strconv.Atoi(key)
+				if err != nil || index < 0 || index >= len(x) {
+					return nil, fmt.Errorf("${%s}: no element %q", ref, strings.Join(path[:i+1], "."))
+				}
+				value = x[index]
+			default:
+				return nil, fmt.Errorf("${%s}: %q is not an object or list", ref, strings.Join(path[:i], "."))
+			}
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("${%s}: no earlier step with a result by that name", ref)
+}
+
+// waitForEndpoint polls until the EL answers, for test harnesses that start
+// the scenario alongside a client still booting
+func (c *EngineClient) waitForEndpoint(ctx context.Context, poll time.Duration) error {
+	for {
+		_, err := c.makeRequest(ctx, "engine_exchangeCapabilities", []interface{}{[]string{}})
+		var rpcErr *RPCError
+		if err == nil || errors.As(err, &rpcErr) {
+			return nil
+		}
+		if !c.sleep(ctx, poll) {
+			return fmt.Errorf("EL did not come up: %v", err)
+		}
+	}
+}
+
+// runScenario executes a scenario file against the EL and exits non-zero if
+// any step fails, so it can gate conformance tests in Hive or Kurtosis
+func runScenario(args []string) error {
+	fs := flag.NewFlagSet("scenario", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	file := fs.String("file", "", "scenario to run")
+	wait := fs.Duration("wait", 0, "wait up to this long for the EL to come up before the first step")
+	keepGoing := fs.Bool("keep-going", false, "run every step even after one fails")
+	resultsPath := fs.String("results", "", "write the report as JSON to this file")
+	timeout := fs.Duration("timeout", 10*time.Minute, "timeout for the whole scenario")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	defer out.batch()()
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	scenario, err := LoadScenario(*file)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if *wait > 0 {
+		waitCtx, cancelWait := context.WithTimeout(ctx, *wait)
+		err := client.waitForEndpoint(waitCtx, time.Second)
+		cancelWait()
+		if err != nil {
+			return err
+		}
+	}
+
+	report := client.RunScenario(ctx, scenario, *keepGoing, func(r StepResult) {
+		rec := record{
+			{"step", r.Name}, {"method", r.Method}, {"status", r.Status},
+			{"pass", r.Pass}, {"skipped", r.Skipped}, {"duration", r.Duration.Round(time.Millisecond)}, {"detail", r.Detail},
+		}
+		out.emit(rec, func(w io.Writer) {
+			switch {
+			case r.Skipped:
+				fmt.Fprintf(w, "SKIP %s\n", r.Name)
+			case r.Pass:
+				fmt.Fprintf(w, "PASS %s %s %s(%s)\n", r.Name, r.Method, optionalLabel(r.Status), r.Duration.Round(time.Millisecond))
+			default:
+				fmt.Fprintf(w, "FAIL %s %s: %s\n", r.Name, r.Method, r.Detail)
+			}
+		})
+	})
+	out.emit(record{{"scenario", report.Name}, {"passed", report.Passed}, {"failed", report.Failed}, {"skipped", report.Skipped}}, func(w io.Writer) {
+		fmt.Fprintf(w, "%s: %d passed, %d failed, %d skipped\n", report.Name, report.Passed, report.Failed, report.Skipped)
+	})
+
+	if *resultsPath != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*resultsPath, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("failed to write results: %v", err)
+		}
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("scenario %s: %d of %d steps failed", report.Name, report.Failed, len(report.Steps))
+	}
+	return nil
+}
+
+// optionalLabel renders s followed by a space, or nothing when s is empty
+func optionalLabel(s string) string {
+	if s == "" {
+		return ""
+	}
+	return s + " "
+}
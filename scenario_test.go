@@ -0,0 +1,78 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testPayload returns a Paris payload whose blockHash matches its fields
+func testPayload(t *testing.T) map[string]interface{} {
+	t.Helper()
+	p := &ExecutionPayload{
+		LogsBloom:     "0x" + strings.Repeat("00", 256),
+		BlockNumber:   "0x1",
+		GasLimit:      "0x1c9c380",
+		GasUsed:       "0x0",
+		Timestamp:     "0x64",
+		ExtraData:     "0x",
+		BaseFeePerGas: "0x7",
+		Transactions:  []string{},
+	}
+	hash, err := ComputeBlockHash(p, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.BlockHash = hash
+	payload, err := payloadMap(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestScenarioStepsGetCallMethodBookkeeping(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	srv := stubEL(t, func(method string, _ []json.RawMessage) (interface{}, *RPCError) {
+		mu.Lock()
+		sent = append(sent, method)
+		mu.Unlock()
+		if strings.HasPrefix(method, "engine_forkchoiceUpdated") {
+			return ForkchoiceUpdatedResult{PayloadStatus: PayloadStatus{Status: StatusValid}}, nil
+		}
+		return PayloadStatus{Status: StatusValid}, nil
+	})
+	c := NewEngineClient(srv.URL, nil, WithoutAuth(), WithBlockHashVerification())
+
+	payload := testPayload(t)
+	bad := testPayload(t)
+	bad["blockHash"] = Hash{0xff}.Hex()
+	args := func(v interface{}) json.RawMessage {
+		raw, _ := json.Marshal(v)
+		return raw
+	}
+	state := ForkChoiceState{HeadBlockHash: MustHexToHash(payload["blockHash"].(string))}
+	s := &Scenario{Steps: []ScenarioStep{
+		{Name: "family", Method: "newPayload", Fork: ForkParis, Args: args(map[string]interface{}{"payload": payload})},
+		{Name: "raw", Method: "engine_forkchoiceUpdatedV1", Params: args([]interface{}{state})},
+		{Name: "bad hash", Method: "engine_newPayloadV1", Params: args([]interface{}{bad})},
+	}}
+	report := c.RunScenario(context.Background(), s, true, nil)
+
+	if !report.Steps[0].Pass || !report.Steps[1].Pass {
+		t.Fatalf("steps failed: %+v", report.Steps)
+	}
+	if report.Steps[2].Pass {
+		t.Fatal("payload with a wrong block hash was not refused")
+	}
+	if want := []string{"engine_newPayloadV1", "engine_forkchoiceUpdatedV1"}; strings.Join(sent, ",") != strings.Join(want, ",") {
+		t.Fatalf("sent %v, want %v", sent, want)
+	}
+	status := c.Status()
+	if status.LastPayload == nil || status.LastForkchoice == nil || status.LastForkchoice.State.HeadBlockHash != state.HeadBlockHash {
+		t.Fatalf("scenario calls were not recorded: %+v", status)
+	}
+}
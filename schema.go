@@ -0,0 +1,229 @@
+package engineclient
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// engineSchema is an OpenRPC document describing Engine API results, derived
+// from the ethereum/execution-apis specification
+//
+//go:embed schemas/engine.json
+var engineSchema []byte
+
+type jsonSchema struct {
+	Ref        string                 `json:"$ref"`
+	Type       string                 `json:"type"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Required   []string               `json:"required"`
+	Items      *jsonSchema            `json:"items"`
+	Pattern    string                 `json:"pattern"`
+	Enum       []interface{}          `json:"enum"`
+	AnyOf      []*jsonSchema          `json:"anyOf"`
+
+	pattern *regexp.Regexp
+}
+
+type openRPCDocument struct {
+	Methods []struct {
+		Name   string `json:"name"`
+		Result struct {
+			Schema *jsonSchema `json:"schema"`
+		} `json:"result"`
+	} `json:"methods"`
+	Components struct {
+		Schemas map[string]*jsonSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+// schemaRegistry resolves method result schemas from the embedded document
+type schemaRegistry struct {
+	methods    map[string]*jsonSchema
+	components map[string]*jsonSchema
+}
+
+var (
+	loadSchemasOnce sync.Once
+	schemas         *schemaRegistry
+	schemasErr      error
+)
+
+func loadSchemas() (*schemaRegistry, error) {
+	loadSchemasOnce.Do(func() {
+		var doc openRPCDocument
+		if err := json.Unmarshal(engineSchema, &doc); err != nil {
+			schemasErr = fmt.Errorf("failed to parse embedded schema: %v", err)
+			return
+		}
+		r := &schemaRegistry{
+			methods:    make(map[string]*jsonSchema),
+			components: doc.Components.Schemas,
+		}
+		for _, m := range doc.Methods {
+			r.methods[m.Name] = m.Result.Schema
+		}
+		for _, s := range r.components {
+			if err := compilePatterns(s); err != nil {
+				schemasErr = err
+				return
+			}
+		}
+		for _, s := range r.methods {
+			if err := compilePatterns(s); err != nil {
+				schemasErr = err
+				return
+			}
+		}
+		schemas = r
+	})
+	return schemas, schemasErr
+}
+
+func compilePatterns(s *jsonSchema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema pattern %q: %v", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for _, p := range s.Properties {
+		if err := compilePatterns(p); err != nil {
+			return err
+		}
+	}
+	for _, o := range s.AnyOf {
+		if err := compilePatterns(o); err != nil {
+			return err
+		}
+	}
+	return compilePatterns(s.Items)
+}
+
+// SchemaViolationError lists the ways a response deviated from the spec
+type SchemaViolationError struct {
+	Method     string
+	Violations []string
+}
+
+func (e *SchemaViolationError) Error() string {
+	return fmt.Sprintf("%s response violates the engine API schema: %s", e.Method, strings.Join(e.Violations, "; "))
+}
+
+// ValidateResult checks a decoded result against the schema for method. It
+// returns nil for methods the embedded document does not describe.
+func ValidateResult(method string, result interface{}) error {
+	r, err := loadSchemas()
+	if err != nil {
+		return err
+	}
+	schema, ok := r.methods[method]
+	if !ok {
+		return nil
+	}
+	if violations := r.validate(schema, result, "result"); len(violations) > 0 {
+		return &SchemaViolationError{Method: method, Violations: violations}
+	}
+	return nil
+}
+
+func (r *schemaRegistry) resolve(s *jsonSchema) *jsonSchema {
+	for s.Ref != "" {
+		s = r.components[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if s == nil {
+			return &jsonSchema{}
+		}
+	}
+	return s
+}
+
+func (r *schemaRegistry) validate(s *jsonSchema, value interface{}, path string) []string {
+	s = r.resolve(s)
+
+	if len(s.AnyOf) > 0 {
+		var first []string
+		for _, option := range s.AnyOf {
+			v := r.validate(option, value, path)
+			if len(v) == 0 {
+				return nil
+			}
+			if first == nil {
+				first = v
+			}
+		}
+		return first
+	}
+
+	var violations []string
+	switch s.Type {
+	case "null":
+		if value != nil {
+			return []string{fmt.Sprintf("%s: expected null", path)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected boolean", path)}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected string", path)}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			violations = append(violations, fmt.Sprintf("%s: %q does not match %s", path, str, s.Pattern))
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array", path)}
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				violations = append(violations, r.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object", path)}
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing required field %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if v, ok := obj[name]; ok {
+				violations = append(violations, r.validate(s.Properties[name], v, path+"."+name)...)
+			}
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violations = append(violations, fmt.Sprintf("%s: %v is not one of %v", path, value, s.Enum))
+		}
+	}
+	return violations
+}
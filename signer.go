@@ -0,0 +1,17 @@
+package engineclient
+
+import "github.com/golang-jwt/jwt/v4"
+
+// TokenSigner signs the claims of an engine API token and returns the
+// compact JWT. Implementations must be safe for concurrent use.
+type TokenSigner interface {
+	Sign(claims jwt.MapClaims) (string, error)
+}
+
+// HS256Signer signs tokens with an in-memory HMAC secret, as the engine API
+// specification requires by default
+type HS256Signer []byte
+
+func (s HS256Signer) Sign(claims jwt.MapClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s))
+}
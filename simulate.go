@@ -0,0 +1,352 @@
+package engineclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SimulatorConfig configures Simulate
+type SimulatorConfig struct {
+	// Fork selects the method versions and the shape of the attributes
+	Fork Fork
+	// SlotTime is the interval between slots, 12s if zero
+	SlotTime time.Duration
+	// BuildTime is how long the EL is given between forkchoiceUpdated and
+	// getPayload
+	BuildTime    time.Duration
+	FeeRecipient Address
+	// SafeLag and FinalizedLag are how many blocks safe and finalized trail
+	// the head; until that many blocks exist they stay at the starting head
+	SafeLag      uint64
+	FinalizedLag uint64
+	// Slots stops the loop after this many slots; zero runs until ctx ends
+	Slots int
+	// OnSlot, if set, is called with the outcome of every slot
+	OnSlot func(SlotResult)
+}
+
+// SlotResult is the outcome of one simulated slot. Block fields are unset
+// when the slot failed before a payload was built.
+type SlotResult struct {
+	Slot     uint64
+	Number   uint64
+	Hash     Hash
+	Txs      int
+	Blobs    int
+	Value    *Wei
+	Status   string
+	Duration time.Duration
+	Err      error
+}
+
+// simulatorState is the chain as the simulated CL sees it
+type simulatorState struct {
+	forkchoice ForkChoiceState
+	number     uint64
+	timestamp  time.Time
+	canonical  map[uint64]Hash
+}
+
+// Simulate acts as a minimal consensus client: every slot it requests a
+// payload on the current head with fresh attributes, fetches it, submits it
+// with newPayload and makes it the new head. A slot that fails is reported
+// and skipped, as a missed proposal would be, and the loop carries on.
+func (c *EngineClient) Simulate(ctx context.Context, cfg SimulatorConfig) error {
+	if cfg.SlotTime <= 0 {
+		cfg.SlotTime = 12 * time.Second
+	}
+	if cfg.BuildTime >= cfg.SlotTime {
+		return fmt.Errorf("build time %s must be shorter than the slot time %s", cfg.BuildTime, cfg.SlotTime)
+	}
+	if _, err := c.methods.Resolve(FamilyNewPayload, cfg.Fork); err != nil {
+		return err
+	}
+	head, err := c.HeadBlock(withPrimaryEndpoint(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to get the starting head: %v", err)
+	}
+	state := &simulatorState{
+		forkchoice: ForkChoiceState{HeadBlockHash: head.Hash, SafeBlockHash: head.Hash, FinalizedBlockHash: head.Hash},
+		number:     head.Number,
+		timestamp:  head.Timestamp,
+		canonical:  map[uint64]Hash{head.Number: head.Hash},
+	}
+
+	next := c.clock.Now()
+	for slot := uint64(1); cfg.Slots == 0 || slot <= uint64(cfg.Slots); slot++ {
+		if !c.sleep(ctx, next.Sub(c.clock.Now())) {
+			return nil
+		}
+		start := c.clock.Now()
+		result := c.simulateSlot(ctx, cfg, state, slot, start)
+		result.Duration = c.clock.Now().Sub(start)
+		if cfg.OnSlot != nil {
+			cfg.OnSlot(result)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		// A slot that overran skips the slots it missed, like a real CL.
+		next = next.Add(cfg.SlotTime)
+		for !next.After(c.clock.Now()) {
+			next = next.Add(cfg.SlotTime)
+		}
+	}
+	return nil
+}
+
+func (c *EngineClient) simulateSlot(ctx context.Context, cfg SimulatorConfig, state *simulatorState, slot uint64, start time.Time) SlotResult {
+	result := SlotResult{Slot: slot}
+	attributes, err := simulatedAttributes(cfg, state, slot, start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	forkchoice := state.forkchoice
+	response, err := c.CallMethod(ctx, FamilyForkchoiceUpdated, cfg.Fork, MethodArgs{State: &forkchoice, Attributes: attributes})
+	var fcu ForkchoiceUpdatedResult
+	if err == nil {
+		err = decodeResult(response, &fcu)
+	}
+	if err == nil && fcu.PayloadID == nil {
+		err = missingPayloadIDError(fcu.PayloadStatus)
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("forkchoiceUpdated: %w", err)
+		return result
+	}
+
+	if !c.sleep(ctx, cfg.BuildTime) {
+		result.Err = ctx.Err()
+		return result
+	}
+	response, err = c.CallMethod(ctx, FamilyGetPayload, cfg.Fork, MethodArgs{PayloadID: *fcu.PayloadID})
+	if err != nil {
+		result.Err = fmt.Errorf("getPayload: %w", err)
+		return result
+	}
+	var envelope struct {
+		ExecutionPayload  map[string]interface{} `json:"executionPayload"`
+		BlockValue        *Wei                   `json:"blockValue"`
+		ExecutionRequests []string               `json:"executionRequests"`
+		BlobsBundle       *struct {
+			Commitments []string `json:"commitments"`
+		} `json:"blobsBundle"`
+	}
+	if cfg.Fork == ForkParis {
+		err = decodeResult(response, &envelope.ExecutionPayload)
+	} else {
+		err = decodeResult(response, &envelope)
+	}
+	if err == nil && envelope.ExecutionPayload == nil {
+		err = fmt.Errorf("response has no execution payload")
+	}
+	var payload *ExecutionPayload
+	if err == nil {
+		payload, err = DecodeExecutionPayload(envelope.ExecutionPayload)
+	}
+	if err == nil {
+		result.Number, err = decodeQuantity(payload.BlockNumber)
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("getPayload: %v", err)
+		return result
+	}
+	result.Hash, result.Txs, result.Value = payload.BlockHash, len(payload.Transactions), envelope.BlockValue
+
+	args := MethodArgs{
+		Payload:               envelope.ExecutionPayload,
+		ParentBeaconBlockRoot: attributes.ParentBeaconBlockRoot,
+		ExecutionRequests:     envelope.ExecutionRequests,
+	}
+	if envelope.BlobsBundle != nil {
+		for _, commitment := range envelope.BlobsBundle.Commitments {
+			raw, err := decodeHex(commitment)
+			if err != nil {
+				result.Err = fmt.Errorf("getPayload: invalid blob commitment: %v", err)
+				return result
+			}
+			args.VersionedHashes = append(args.VersionedHashes, kzgToVersionedHash(raw))
+		}
+		result.Blobs = len(args.VersionedHashes)
+	}
+	response, err = c.CallMethod(ctx, FamilyNewPayload, cfg.Fork, args)
+	var status PayloadStatus
+	if err == nil {
+		err = decodeResult(response, &status)
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("newPayload: %w", err)
+		return result
+	}
+	result.Status = status.Status
+	if status.Status != StatusValid {
+		result.Err = fmt.Errorf("newPayload returned %s", status.Status)
+		if status.ValidationError != nil {
+			result.Err = fmt.Errorf("newPayload returned %s: %s", status.Status, *status.ValidationError)
+		}
+		return result
+	}
+
+	// Make the new block the head, moving safe and finalized once the chain
+	// is long enough for their lag.
+	state.canonical[result.Number] = payload.BlockHash
+	next := ForkChoiceState{
+		HeadBlockHash:      payload.BlockHash,
+		SafeBlockHash:      state.forkchoice.SafeBlockHash,
+		FinalizedBlockHash: state.forkchoice.FinalizedBlockHash,
+	}
+	if hash, ok := lagged(state.canonical, result.Number, cfg.SafeLag); ok {
+		next.SafeBlockHash = hash
+	}
+	if hash, ok := lagged(state.canonical, result.Number, cfg.FinalizedLag); ok {
+		next.FinalizedBlockHash = hash
+	}
+	response, err = c.CallMethod(ctx, FamilyForkchoiceUpdated, cfg.Fork, MethodArgs{State: &next})
+	if err == nil {
+		err = decodeResult(response, &fcu)
+	}
+	if err == nil && fcu.PayloadStatus.Status != StatusValid {
+		err = fmt.Errorf("status %s", fcu.PayloadStatus.Status)
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("forkchoiceUpdated to the new head: %w", err)
+		return result
+	}
+
+	state.forkchoice, state.number, state.timestamp = next, result.Number, attributes.Timestamp
+	// Only the blocks the lags can still reach need to be remembered.
+	keep := max(cfg.SafeLag, cfg.FinalizedLag)
+	for number := range state.canonical {
+		if number+keep < state.number {
+			delete(state.canonical, number)
+		}
+	}
+	return result
+}
+
+// simulatedAttributes builds the attributes for a slot starting at start,
+// with a random prevRandao and a timestamp past the parent's
+func simulatedAttributes(cfg SimulatorConfig, state *simulatorState, slot uint64, start time.Time) (*PayloadAttributes, error) {
+	timestamp := start.Truncate(time.Second)
+	if !timestamp.After(state.timestamp) {
+		timestamp = state.timestamp.Add(time.Second)
+	}
+	var randao Hash
+	if _, err := rand.Read(randao[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate prevRandao: %v", err)
+	}
+	b := NewPayloadAttributes().
+		WithTimestamp(timestamp).
+		WithRandao(randao).
+		WithFeeRecipient(cfg.FeeRecipient)
+	switch cfg.Fork {
+	case ForkParis:
+	case ForkShanghai:
+		b = b.WithWithdrawals()
+	default:
+		// There is no beacon chain, so the root only has to be unique.
+		root := Hash(keccak256(binary.BigEndian.AppendUint64([]byte("slot"), slot)))
+		b = b.WithWithdrawals().WithParentBeaconBlockRoot(root)
+	}
+	return b.Build()
+}
+
+// kzgToVersionedHash derives the EIP-4844 versioned hash of a blob commitment
+func kzgToVersionedHash(commitment []byte) Hash {
+	h := Hash(sha256.Sum256(commitment))
+	h[0] = 0x01
+	return h
+}
+
+// runSimulate drives the EL as a self-contained fake CL, producing a block
+// every slot, for private devnets without a beacon chain
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	forkName := fs.String("fork", "cancun", "fork whose method versions and attributes to use")
+	slotTime := fs.Duration("slot-time", 12*time.Second, "time between slots")
+	buildTime := fs.Duration("build-time", time.Second, "time the EL is given to build each payload")
+	feeRecipient := fs.String("fee-recipient", "0x0000000000000000000000000000000000000000", "suggested fee recipient")
+	safeLag := fs.Uint64("safe-lag", 32, "blocks between head and safe")
+	finalizedLag := fs.Uint64("finalized-lag", 64, "blocks between head and finalized")
+	slots := fs.Int("slots", 0, "stop after this many slots (0 to run until interrupted)")
+	statusAddr := fs.String("status-addr", "", "serve /healthz, /readyz and /status on this address")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	fork, err := ParseFork(*forkName)
+	if err != nil {
+		return err
+	}
+	recipient, err := HexToAddress(*feeRecipient)
+	if err != nil {
+		return fmt.Errorf("invalid -fee-recipient: %v", err)
+	}
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	startStatusServer(*statusAddr, client, fork)
+
+	invalid := 0
+	err = client.Simulate(context.Background(), SimulatorConfig{
+		Fork:         fork,
+		SlotTime:     *slotTime,
+		BuildTime:    *buildTime,
+		FeeRecipient: recipient,
+		SafeLag:      *safeLag,
+		FinalizedLag: *finalizedLag,
+		Slots:        *slots,
+		OnSlot: func(r SlotResult) {
+			if r.Status == StatusInvalid || r.Status == StatusInvalidBlockHash {
+				invalid++
+			}
+			rec := record{
+				{"slot", r.Slot}, {"block", nil}, {"hash", nil}, {"txs", nil}, {"blobs", nil},
+				{"valueEth", nil}, {"status", r.Status}, {"duration", r.Duration.Round(time.Millisecond)}, {"error", r.Err},
+			}
+			if r.Hash != (Hash{}) {
+				rec[1].value, rec[2].value, rec[3].value, rec[4].value = r.Number, r.Hash, r.Txs, r.Blobs
+			}
+			if r.Value != nil {
+				rec[5].value = r.Value.Ether()
+			}
+			out.emit(rec, func(w io.Writer) {
+				if r.Err != nil {
+					fmt.Fprintf(w, "slot %d: %v\n", r.Slot, r.Err)
+					return
+				}
+				value := "0"
+				if r.Value != nil {
+					value = r.Value.Ether()
+				}
+				fmt.Fprintf(w, "slot %d: block %d %s txs=%d blobs=%d value=%s ETH (%s)\n",
+					r.Slot, r.Number, r.Hash, r.Txs, r.Blobs, value, r.Duration.Round(time.Millisecond))
+			})
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return statusError(StatusInvalid, "%d built payloads were rejected as invalid", invalid)
+	}
+	return nil
+}
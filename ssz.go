@@ -0,0 +1,449 @@
+package engineclient
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+const (
+	bellatrixPayloadFixedSize = 508
+	capellaPayloadFixedSize   = 512
+	denebPayloadFixedSize     = 528
+
+	withdrawalSSZSize         = 44
+	maxExtraDataBytes         = 32
+	maxTransactionsPerPayload = 1 << 20
+	maxBytesPerTransaction    = 1 << 30
+	maxWithdrawalsPerPayload  = 16
+)
+
+// MarshalSSZ encodes the withdrawal as its 44-byte SSZ container
+func (w *Withdrawal) MarshalSSZ() ([]byte, error) {
+	e := &sszEncoder{}
+	e.uint64("index", w.Index)
+	e.uint64("validatorIndex", w.ValidatorIndex)
+	e.raw(w.Address[:])
+	e.uint64("amount", w.Amount)
+	return e.buf, e.err
+}
+
+// UnmarshalSSZ decodes a 44-byte SSZ withdrawal container
+func (w *Withdrawal) UnmarshalSSZ(b []byte) error {
+	if len(b) != withdrawalSSZSize {
+		return fmt.Errorf("withdrawal has size %d, want %d", len(b), withdrawalSSZSize)
+	}
+	w.Index = fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(b[0:8]))
+	w.ValidatorIndex = fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(b[8:16]))
+	copy(w.Address[:], b[16:36])
+	w.Amount = fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(b[36:44]))
+	return nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the withdrawal
+func (w *Withdrawal) HashTreeRoot() ([32]byte, error) {
+	b, err := w.MarshalSSZ()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	chunks := make([][32]byte, 4)
+	copy(chunks[0][:], b[0:8])
+	copy(chunks[1][:], b[8:16])
+	copy(chunks[2][:], b[16:36])
+	copy(chunks[3][:], b[36:44])
+	return merkleize(chunks, 4), nil
+}
+
+// sszFixedSize returns the size of the fixed part of the payload's SSZ
+// container, which depends on the fork the payload belongs to
+func (p *ExecutionPayload) sszFixedSize() int {
+	switch {
+	case p.BlobGasUsed != nil || p.ExcessBlobGas != nil:
+		return denebPayloadFixedSize
+	case p.Withdrawals != nil:
+		return capellaPayloadFixedSize
+	default:
+		return bellatrixPayloadFixedSize
+	}
+}
+
+// MarshalSSZ encodes the payload as the consensus-layer ExecutionPayload
+// container of the fork implied by the fields that are set
+func (p *ExecutionPayload) MarshalSSZ() ([]byte, error) {
+	fixedSize := p.sszFixedSize()
+	if fixedSize == denebPayloadFixedSize && (p.BlobGasUsed == nil || p.ExcessBlobGas == nil) {
+		return nil, fmt.Errorf("blobGasUsed and excessBlobGas must be set together")
+	}
+
+	extraData, err := decodeHex(p.ExtraData)
+	if err != nil {
+		return nil, fmt.Errorf("extraData: %v", err)
+	}
+	if len(extraData) > maxExtraDataBytes {
+		return nil, fmt.Errorf("extraData has %d bytes, limit is %d", len(extraData), maxExtraDataBytes)
+	}
+	transactions, err := p.encodeSSZTransactions()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Withdrawals) > maxWithdrawalsPerPayload {
+		return nil, fmt.Errorf("payload has %d withdrawals, limit is %d", len(p.Withdrawals), maxWithdrawalsPerPayload)
+	}
+	var withdrawals []byte
+	for i := range p.Withdrawals {
+		b, err := p.Withdrawals[i].MarshalSSZ()
+		if err != nil {
+			return nil, fmt.Errorf("withdrawal %d: %v", i, err)
+		}
+		withdrawals = append(withdrawals, b...)
+	}
+
+	e := &sszEncoder{}
+	e.raw(p.ParentHash[:])
+	e.raw(p.FeeRecipient[:])
+	e.raw(p.StateRoot[:])
+	e.raw(p.ReceiptsRoot[:])
+	e.fixed("logsBloom", p.LogsBloom, 256)
+	e.raw(p.PrevRandao[:])
+	e.uint64("blockNumber", p.BlockNumber)
+	e.uint64("gasLimit", p.GasLimit)
+	e.uint64("gasUsed", p.GasUsed)
+	e.uint64("timestamp", p.Timestamp)
+	e.offset(fixedSize)
+	e.uint256("baseFeePerGas", p.BaseFeePerGas)
+	e.raw(p.BlockHash[:])
+	e.offset(fixedSize + len(extraData))
+	if fixedSize >= capellaPayloadFixedSize {
+		e.offset(fixedSize + len(extraData) + len(transactions))
+	}
+	if fixedSize == denebPayloadFixedSize {
+		e.uint64("blobGasUsed", *p.BlobGasUsed)
+		e.uint64("excessBlobGas", *p.ExcessBlobGas)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	out := append(e.buf, extraData...)
+	out = append(out, transactions...)
+	return append(out, withdrawals...), nil
+}
+
+func (p *ExecutionPayload) encodeSSZTransactions() ([]byte, error) {
+	if len(p.Transactions) > maxTransactionsPerPayload {
+		return nil, fmt.Errorf("payload has %d transactions, limit is %d", len(p.Transactions), maxTransactionsPerPayload)
+	}
+	offsets := make([]byte, 4*len(p.Transactions))
+	var body []byte
+	for i, tx := range p.Transactions {
+		b, err := decodeHex(tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		binary.LittleEndian.PutUint32(offsets[4*i:], uint32(len(offsets)+len(body)))
+		body = append(body, b...)
+	}
+	return append(offsets, body...), nil
+}
+
+// UnmarshalSSZ decodes a consensus-layer ExecutionPayload container. The fork
+// is detected from the extraData offset, which always equals the size of the
+// fixed part.
+func (p *ExecutionPayload) UnmarshalSSZ(b []byte) error {
+	if len(b) < bellatrixPayloadFixedSize {
+		return fmt.Errorf("payload has size %d, minimum is %d", len(b), bellatrixPayloadFixedSize)
+	}
+	fixedSize := int(binary.LittleEndian.Uint32(b[436:440]))
+	switch fixedSize {
+	case bellatrixPayloadFixedSize, capellaPayloadFixedSize, denebPayloadFixedSize:
+	default:
+		return fmt.Errorf("invalid extraData offset %d", fixedSize)
+	}
+	if len(b) < fixedSize {
+		return fmt.Errorf("payload has size %d, fixed part is %d", len(b), fixedSize)
+	}
+
+	d := &sszDecoder{buf: b}
+	d.read(p.ParentHash[:])
+	d.read(p.FeeRecipient[:])
+	d.read(p.StateRoot[:])
+	d.read(p.ReceiptsRoot[:])
+	p.LogsBloom = d.hex(256)
+	d.read(p.PrevRandao[:])
+	p.BlockNumber = d.quantity()
+	p.GasLimit = d.quantity()
+	p.GasUsed = d.quantity()
+	p.Timestamp = d.quantity()
+	extraOffset := d.offset()
+	p.BaseFeePerGas = d.uint256()
+	d.read(p.BlockHash[:])
+	txOffset := d.offset()
+	end := len(b)
+	withdrawalsOffset := end
+	if fixedSize >= capellaPayloadFixedSize {
+		withdrawalsOffset = d.offset()
+	}
+	p.BlobGasUsed, p.ExcessBlobGas = nil, nil
+	if fixedSize == denebPayloadFixedSize {
+		blobGasUsed, excessBlobGas := d.quantity(), d.quantity()
+		p.BlobGasUsed, p.ExcessBlobGas = &blobGasUsed, &excessBlobGas
+	}
+
+	if extraOffset > txOffset || txOffset > withdrawalsOffset || withdrawalsOffset > end {
+		return fmt.Errorf("invalid variable-length offsets")
+	}
+	if txOffset-extraOffset > maxExtraDataBytes {
+		return fmt.Errorf("extraData has %d bytes, limit is %d", txOffset-extraOffset, maxExtraDataBytes)
+	}
+	p.ExtraData = encodeHex(b[extraOffset:txOffset])
+
+	transactions, err := decodeSSZTransactions(b[txOffset:withdrawalsOffset])
+	if err != nil {
+		return err
+	}
+	p.Transactions = transactions
+
+	p.Withdrawals = nil
+	if fixedSize >= capellaPayloadFixedSize {
+		raw := b[withdrawalsOffset:]
+		if len(raw)%withdrawalSSZSize != 0 || len(raw)/withdrawalSSZSize > maxWithdrawalsPerPayload {
+			return fmt.Errorf("invalid withdrawals length %d", len(raw))
+		}
+		p.Withdrawals = make([]Withdrawal, len(raw)/withdrawalSSZSize)
+		for i := range p.Withdrawals {
+			if err := p.Withdrawals[i].UnmarshalSSZ(raw[i*withdrawalSSZSize : (i+1)*withdrawalSSZSize]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeSSZTransactions(b []byte) ([]string, error) {
+	transactions := []string{}
+	if len(b) == 0 {
+		return transactions, nil
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("invalid transactions length %d", len(b))
+	}
+	first := int(binary.LittleEndian.Uint32(b))
+	if first == 0 || first%4 != 0 || first > len(b) || first/4 > maxTransactionsPerPayload {
+		return nil, fmt.Errorf("invalid first transaction offset %d", first)
+	}
+	count := first / 4
+	for i := 0; i < count; i++ {
+		start := int(binary.LittleEndian.Uint32(b[4*i:]))
+		end := len(b)
+		if i+1 < count {
+			end = int(binary.LittleEndian.Uint32(b[4*(i+1):]))
+		}
+		if start > end || end > len(b) || end-start > maxBytesPerTransaction {
+			return nil, fmt.Errorf("invalid offset for transaction %d", i)
+		}
+		transactions = append(transactions, encodeHex(b[start:end]))
+	}
+	return transactions, nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the payload, which is the
+// value committed to by the beacon block body
+func (p *ExecutionPayload) HashTreeRoot() ([32]byte, error) {
+	// The SSZ encoding validates every field and lays the fixed-width values
+	// out at known positions, so basic fields are chunked straight from it.
+	b, err := p.MarshalSSZ()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	fixedSize := p.sszFixedSize()
+
+	var fields [][32]byte
+	chunk := func(data []byte) {
+		var c [32]byte
+		copy(c[:], data)
+		fields = append(fields, c)
+	}
+	chunk(b[0:32])   // parentHash
+	chunk(b[32:52])  // feeRecipient
+	chunk(b[52:84])  // stateRoot
+	chunk(b[84:116]) // receiptsRoot
+	fields = append(fields, merkleize(packChunks(b[116:372]), 8))
+	chunk(b[372:404]) // prevRandao
+	chunk(b[404:412]) // blockNumber
+	chunk(b[412:420]) // gasLimit
+	chunk(b[420:428]) // gasUsed
+	chunk(b[428:436]) // timestamp
+
+	extraData, _ := decodeHex(p.ExtraData)
+	fields = append(fields, mixInLength(merkleize(packChunks(extraData), 1), uint64(len(extraData))))
+	chunk(b[440:472]) // baseFeePerGas
+	chunk(b[472:504]) // blockHash
+
+	txRoots := make([][32]byte, len(p.Transactions))
+	for i, tx := range p.Transactions {
+		raw, _ := decodeHex(tx)
+		txRoots[i] = mixInLength(merkleize(packChunks(raw), (maxBytesPerTransaction+31)/32), uint64(len(raw)))
+	}
+	fields = append(fields, mixInLength(merkleize(txRoots, maxTransactionsPerPayload), uint64(len(txRoots))))
+
+	if fixedSize >= capellaPayloadFixedSize {
+		withdrawalRoots := make([][32]byte, len(p.Withdrawals))
+		for i := range p.Withdrawals {
+			if withdrawalRoots[i], err = p.Withdrawals[i].HashTreeRoot(); err != nil {
+				return [32]byte{}, err
+			}
+		}
+		fields = append(fields, mixInLength(merkleize(withdrawalRoots, maxWithdrawalsPerPayload), uint64(len(withdrawalRoots))))
+	}
+	if fixedSize == denebPayloadFixedSize {
+		chunk(b[512:520]) // blobGasUsed
+		chunk(b[520:528]) // excessBlobGas
+	}
+	return merkleize(fields, uint64(len(fields))), nil
+}
+
+// sszEncoder appends little-endian SSZ fields, stopping at the first
+// malformed value
+type sszEncoder struct {
+	buf []byte
+	err error
+}
+
+func (e *sszEncoder) fail(name string, err error) {
+	if e.err == nil {
+		e.err = fmt.Errorf("%s: %v", name, err)
+	}
+}
+
+func (e *sszEncoder) raw(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *sszEncoder) fixed(name, value string, n int) {
+	b, err := decodeFixedHex(value, n)
+	if err != nil {
+		e.fail(name, err)
+		return
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *sszEncoder) uint64(name, value string) {
+	v, err := decodeQuantity(value)
+	if err != nil {
+		e.fail(name, err)
+		return
+	}
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, v)
+}
+
+func (e *sszEncoder) uint256(name, value string) {
+	v, err := decodeBigQuantity(value)
+	if err != nil {
+		e.fail(name, err)
+		return
+	}
+	be := v.Bytes()
+	if len(be) > 32 {
+		e.fail(name, fmt.Errorf("value overflows uint256"))
+		return
+	}
+	var le [32]byte
+	for i, c := range be {
+		le[len(be)-1-i] = c
+	}
+	e.buf = append(e.buf, le[:]...)
+}
+
+func (e *sszEncoder) offset(v int) {
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(v))
+}
+
+// sszDecoder reads fixed-size SSZ fields sequentially; callers check the
+// buffer length up front
+type sszDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *sszDecoder) next(n int) []byte {
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *sszDecoder) read(dst []byte) {
+	copy(dst, d.next(len(dst)))
+}
+
+func (d *sszDecoder) hex(n int) string {
+	return encodeHex(d.next(n))
+}
+
+func (d *sszDecoder) quantity() string {
+	return fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(d.next(8)))
+}
+
+func (d *sszDecoder) uint256() string {
+	le := d.next(32)
+	be := make([]byte, 32)
+	for i, c := range le {
+		be[31-i] = c
+	}
+	return "0x" + new(big.Int).SetBytes(be).Text(16)
+}
+
+func (d *sszDecoder) offset() int {
+	return int(binary.LittleEndian.Uint32(d.next(4)))
+}
+
+var zeroHashes = func() [][32]byte {
+	hashes := make([][32]byte, 64)
+	for i := 1; i < len(hashes); i++ {
+		hashes[i] = hashPair(hashes[i-1], hashes[i-1])
+	}
+	return hashes
+}()
+
+func hashPair(a, b [32]byte) [32]byte {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
+
+// merkleize computes the root of chunks padded with zero chunks up to limit
+func merkleize(chunks [][32]byte, limit uint64) [32]byte {
+	depth := 0
+	for uint64(1)<<depth < limit {
+		depth++
+	}
+	if len(chunks) == 0 {
+		return zeroHashes[depth]
+	}
+	layer := chunks
+	for d := 0; d < depth; d++ {
+		next := make([][32]byte, (len(layer)+1)/2)
+		for i := range next {
+			right := zeroHashes[d]
+			if 2*i+1 < len(layer) {
+				right = layer[2*i+1]
+			}
+			next[i] = hashPair(layer[2*i], right)
+		}
+		layer = next
+	}
+	return layer[0]
+}
+
+func mixInLength(root [32]byte, length uint64) [32]byte {
+	var l [32]byte
+	binary.LittleEndian.PutUint64(l[:], length)
+	return hashPair(root, l)
+}
+
+// packChunks splits bytes into zero-padded 32-byte chunks
+func packChunks(b []byte) [][32]byte {
+	chunks := make([][32]byte, (len(b)+31)/32)
+	for i := range chunks {
+		copy(chunks[i][:], b[i*32:])
+	}
+	return chunks
+}
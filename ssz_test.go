@@ -0,0 +1,82 @@
+package engineclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// mainnetPayload is a newPayload call taken from a mainnet beacon block,
+// converted to the Engine API JSON encoding
+type mainnetPayload struct {
+	ExecutionPayload      ExecutionPayload `json:"executionPayload"`
+	ParentBeaconBlockRoot *Hash            `json:"parentBeaconBlockRoot"`
+}
+
+// loadMainnetPayload reads one of the testdata payloads: block 18189758
+// (Shanghai, beacon slot 7378495) or 19431837 (Cancun, slot 8631513), the
+// blocks go-ethereum's beacon/types tests use
+func loadMainnetPayload(t *testing.T, file string) mainnetPayload {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p mainnetPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestExecutionPayloadSSZ(t *testing.T) {
+	// The expected encodings and roots are those of the consensus-layer
+	// ExecutionPayload containers as computed by go-eth2-client, whose SSZ
+	// code is checked against the consensus-spec tests. The encodings are
+	// compared by length and sha256.
+	tests := []struct {
+		file     string
+		size     int
+		digest   string
+		wantRoot string
+	}{
+		{"mainnet_shanghai.json", 41160,
+			"889a5383baa56cc666ac0f07f6a2510bc08c5ec8bcf61ecb299f3f41c92856e7",
+			"12ec2e97a89678e75ff47b944546e2af986b6319df71a7955f1d54a96bc80095"},
+		{"mainnet_cancun.json", 416002,
+			"d2a59ad8ef48a335b45561d517d0b834d71f989a789c498047ffd301eda857f1",
+			"2ec695b31641b214473f4ac7ac61a1167ed07e3c8ebce508153e23b97adbaae2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			p := loadMainnetPayload(t, tt.file).ExecutionPayload
+			b, err := p.MarshalSSZ()
+			if err != nil {
+				t.Fatal(err)
+			}
+			digest := sha256.Sum256(b)
+			if len(b) != tt.size || hex.EncodeToString(digest[:]) != tt.digest {
+				t.Fatalf("encoded %d bytes with sha256 %x, want %d bytes with sha256 %s", len(b), digest, tt.size, tt.digest)
+			}
+			root, err := p.HashTreeRoot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(root[:]); got != tt.wantRoot {
+				t.Fatalf("hash tree root %s, want %s", got, tt.wantRoot)
+			}
+
+			var decoded ExecutionPayload
+			if err := decoded.UnmarshalSSZ(b); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, p) {
+				t.Fatal("decoding the SSZ encoding did not give back the payload")
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// readinessTimeout bounds the EL calls made by a single /readyz probe
+const readinessTimeout = 5 * time.Second
+
+// callStatus remembers the outcome of the most recent forkchoiceUpdated and
+// newPayload calls for the status server
+type callStatus struct {
+	mu            sync.Mutex
+	forkchoice    *ForkChoiceState
+	forkchoiceAt  time.Time
+	forkchoiceRes *PayloadStatus
+	payloadHash   *Hash
+	payloadAt     time.Time
+	payloadStatus *PayloadStatus
+	// applied is the last forkchoice state the EL accepted as VALID
+	applied *ForkChoiceState
+}
+
+func (s *callStatus) recordForkchoice(state ForkChoiceState, status PayloadStatus, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forkchoice, s.forkchoiceRes, s.forkchoiceAt = &state, &status, now
+	if status.Status == StatusValid {
+		s.applied = &state
+	}
+}
+
+// appliedForkchoice returns the last forkchoice state the EL accepted
+func (s *callStatus) appliedForkchoice() (ForkChoiceState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.applied == nil {
+		return ForkChoiceState{}, false
+	}
+	return *s.applied, true
+}
+
+// recordPayload stores the status of a newPayload response, ignoring
+// responses that carry no status
+func (s *callStatus) recordPayload(payload map[string]interface{}, response map[string]interface{}, now time.Time) {
+	var status PayloadStatus
+	if decodeResult(response, &status) != nil || status.Status == "" {
+		return
+	}
+	var hash *Hash
+	if h, err := HexToHash(stringField(payload, "blockHash")); err == nil {
+		hash = &h
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payloadHash, s.payloadStatus, s.payloadAt = hash, &status, now
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+type forkchoiceReport struct {
+	State  *ForkChoiceState `json:"state"`
+	Status *PayloadStatus   `json:"status"`
+	At     time.Time        `json:"at"`
+}
+
+type payloadReport struct {
+	BlockHash *Hash          `json:"blockHash"`
+	Status    *PayloadStatus `json:"status"`
+	At        time.Time      `json:"at"`
+}
+
+// StatusReport is served on /status
+type StatusReport struct {
+	Endpoint       string            `json:"endpoint"`
+	ReadEndpoints  []EndpointStatus  `json:"readEndpoints,omitempty"`
+	LastForkchoice *forkchoiceReport `json:"lastForkchoice"`
+	LastPayload    *payloadReport    `json:"lastPayload"`
+}
+
+// Status returns the most recent forkchoiceUpdated and newPayload outcomes
+func (c *EngineClient) Status() StatusReport {
+	s := &c.status
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := StatusReport{Endpoint: c.endpoint}
+	if c.balancer != nil {
+		report.ReadEndpoints = c.balancer.status(c.clock.Now())
+	}
+	if s.forkchoice != nil {
+		report.LastForkchoice = &forkchoiceReport{State: s.forkchoice, Status: s.forkchoiceRes, At: s.forkchoiceAt}
+	}
+	if s.payloadStatus != nil {
+		report.LastPayload = &payloadReport{BlockHash: s.payloadHash, Status: s.payloadStatus, At: s.payloadAt}
+	}
+	return report
+}
+
+// ReadinessReport is served on /readyz
+type ReadinessReport struct {
+	Ready        bool     `json:"ready"`
+	Reachable    bool     `json:"reachable"`
+	Synced       bool     `json:"synced"`
+	Capabilities bool     `json:"capabilities"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// Readiness checks that the EL answers, reports itself synced via
+// eth_syncing and supports every method the given forks need
+func (c *EngineClient) Readiness(ctx context.Context, forks ...Fork) ReadinessReport {
+	var report ReadinessReport
+	progress, err := c.SyncStatus(ctx)
+	if err == nil {
+		report.Reachable = true
+		report.Synced = !progress.Syncing
+		if !report.Synced {
+			report.Errors = append(report.Errors, "EL is syncing")
+		}
+	}
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	report.Capabilities = true
+	if len(forks) > 0 {
+		if _, err := c.CheckCapabilities(ctx, forks...); err != nil {
+			report.Capabilities = false
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+	report.Ready = report.Reachable && report.Synced && report.Capabilities
+	return report
+}
+
+// StatusHandler serves /healthz, which succeeds while the process runs,
+// /readyz, which fails with 503 until Readiness passes, and /status, so the
+// tool can run under orchestrators with real probes
+func (c *EngineClient) StatusHandler(forks ...Fork) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := c.withTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+		report := c.Readiness(ctx, forks...)
+		code := http.StatusOK
+		if !report.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, report)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// startStatusServer serves the client's status endpoints on addr in the
+// background for CLI commands, reporting on stderr if the listener fails
+func startStatusServer(addr string, client *EngineClient, forks ...Fork) {
+	if addr == "" {
+		return
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           client.StatusHandler(forks...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			fmt.Fprintf(os.Stderr, "Status server on %s stopped: %v\n", addr, err)
+		}
+	}()
+}
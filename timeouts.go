@@ -0,0 +1,97 @@
+package engineclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultCallTimeout bounds calls to methods without a spec timeout, such as
+// the eth_ and debug_ namespaces
+const defaultCallTimeout = 10 * time.Second
+
+// SpecTimeouts lists the timeouts the Engine API specification sets for each
+// method family, keyed by method name without its version suffix
+var SpecTimeouts = map[string]time.Duration{
+	"engine_newPayload":                      8 * time.Second,
+	"engine_forkchoiceUpdated":               8 * time.Second,
+	"engine_getPayload":                      time.Second,
+	"engine_getPayloadBodiesByHash":          10 * time.Second,
+	"engine_getPayloadBodiesByRange":         10 * time.Second,
+	"engine_exchangeCapabilities":            time.Second,
+	"engine_exchangeTransitionConfiguration": time.Second,
+	"engine_getClientVersion":                time.Second,
+	"engine_getBlobs":                        time.Second,
+}
+
+// TimeoutError reports a call that did not complete within the limit for its
+// method. Spec is set when the limit is the one the Engine API specification
+// defines rather than an override or the client default.
+type TimeoutError struct {
+	Method string
+	Limit  time.Duration
+	Spec   bool
+	Err    error
+}
+
+func (e *TimeoutError) Error() string {
+	source := "configured"
+	if e.Spec {
+		source = "Engine API spec"
+	}
+	return fmt.Sprintf("%s did not complete within %s (%s limit): %v", e.Method, e.Limit, source, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() []error {
+	return []error{ErrTimeout, e.Err}
+}
+
+// methodFamily strips the version suffix, turning engine_newPayloadV3 into
+// engine_newPayload
+func methodFamily(method string) string {
+	i := strings.LastIndex(method, "V")
+	if i <= 0 || i == len(method)-1 {
+		return method
+	}
+	for _, r := range method[i+1:] {
+		if r < '0' || r > '9' {
+			return method
+		}
+	}
+	return method[:i]
+}
+
+// callTimeout returns the limit for one attempt at method and whether it is
+// the spec limit. Overrides for the exact method win over overrides for its
+// family, which win over the spec.
+func (c *EngineClient) callTimeout(method string) (time.Duration, bool) {
+	family := methodFamily(method)
+	if d, ok := c.timeouts[method]; ok {
+		return d, false
+	}
+	if d, ok := c.timeouts[family]; ok {
+		return d, false
+	}
+	if d, ok := SpecTimeouts[family]; ok {
+		return d, true
+	}
+	if c.defaultTimeout > 0 {
+		return c.defaultTimeout, false
+	}
+	return defaultCallTimeout, false
+}
+
+// withCallTimeout runs send under the method's timeout, turning a deadline
+// hit by that timeout, rather than by ctx, into a *TimeoutError
+func (c *EngineClient) withCallTimeout(ctx context.Context, method string, send func(context.Context) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	limit, spec := c.callTimeout(method)
+	callCtx, cancel := c.withTimeout(ctx, limit)
+	defer cancel()
+	result, err := send(callCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return nil, &TimeoutError{Method: method, Limit: limit, Spec: spec, Err: err}
+	}
+	return result, err
+}
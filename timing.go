@@ -0,0 +1,120 @@
+package engineclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// CallTiming breaks down where the time of one HTTP exchange with the EL went.
+// Phases that did not happen, such as DNS and connect on a reused
+// connection, are zero.
+type CallTiming struct {
+	Method   string
+	ID       uint64
+	Endpoint string
+	Start    time.Time
+
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// Wait runs from the request being fully written to the first response
+	// byte, which is mostly time spent processing in the EL
+	Wait time.Duration
+	// TTFB runs from the start of the exchange to the first response byte
+	TTFB time.Duration
+	// Decode runs from the first response byte until the body was read and
+	// decoded
+	Decode     time.Duration
+	Total      time.Duration
+	ReusedConn bool
+}
+
+func (t CallTiming) logAttrs() []any {
+	return []any{
+		"method", t.Method, "id", t.ID, "endpoint", t.Endpoint,
+		"dns", t.DNS, "connect", t.Connect, "tls", t.TLS,
+		"wait", t.Wait, "ttfb", t.TTFB, "decode", t.Decode,
+		"total", t.Total, "reused", t.ReusedConn,
+	}
+}
+
+// callTracer collects httptrace events for one exchange. Dial events may fire
+// on the transport's goroutines, so every field is guarded.
+type callTracer struct {
+	mu                       sync.Mutex
+	start                    time.Time
+	dnsStart, dnsDone        time.Time
+	connectStart, connectEnd time.Time
+	tlsStart, tlsDone        time.Time
+	wroteRequest, firstByte  time.Time
+	reused                   bool
+}
+
+func newCallTracer() *callTracer {
+	return &callTracer{start: time.Now()}
+}
+
+// withTrace returns ctx with the tracer attached as its httptrace hooks
+func (t *callTracer) withTrace(ctx context.Context) context.Context {
+	at := func(field *time.Time) {
+		t.mu.Lock()
+		if field.IsZero() {
+			*field = time.Now()
+		}
+		t.mu.Unlock()
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { at(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { at(&t.dnsDone) },
+		ConnectStart:      func(string, string) { at(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { at(&t.connectEnd) },
+		TLSHandshakeStart: func() { at(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { at(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { at(&t.wroteRequest) },
+		GotFirstResponseByte: func() { at(&t.firstByte) },
+	})
+}
+
+// timing summarises the exchange as of now
+func (t *callTracer) timing(call requestInfo) CallTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	span := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from)
+	}
+	return CallTiming{
+		Method:     call.method,
+		ID:         call.id,
+		Endpoint:   call.endpoint,
+		Start:      t.start,
+		DNS:        span(t.dnsStart, t.dnsDone),
+		Connect:    span(t.connectStart, t.connectEnd),
+		TLS:        span(t.tlsStart, t.tlsDone),
+		Wait:       span(t.wroteRequest, t.firstByte),
+		TTFB:       span(t.start, t.firstByte),
+		Decode:     span(t.firstByte, now),
+		Total:      now.Sub(t.start),
+		ReusedConn: t.reused,
+	}
+}
+
+// reportTiming logs the breakdown at debug level and passes it to the timing
+// hook, if one is set
+func (c *EngineClient) reportTiming(timing CallTiming) {
+	c.logger.Debug("engine call timing", timing.logAttrs()...)
+	if c.timingHook != nil {
+		c.timingHook(timing)
+	}
+}
@@ -0,0 +1,118 @@
+package engineclient
+
+import "golang.org/x/crypto/sha3"
+
+// keccak256 hashes the concatenation of data
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+type trieItem struct {
+	key   []byte // nibbles
+	value []byte
+}
+
+// deriveListRoot computes the Merkle-Patricia trie root of a list keyed by
+// RLP-encoded index, as used for the transactions and withdrawals roots
+func deriveListRoot(values [][]byte) []byte {
+	items := make([]trieItem, len(values))
+	for i, v := range values {
+		items[i] = trieItem{key: toNibbles(rlpUint(uint64(i))), value: v}
+	}
+	return keccak256(trieNode(items, 0))
+}
+
+// trieNode returns the RLP encoding of the node holding items below depth
+func trieNode(items []trieItem, depth int) []byte {
+	switch len(items) {
+	case 0:
+		return rlpBytes(nil)
+	case 1:
+		return rlpList(rlpBytes(hexPrefix(items[0].key[depth:], true)), rlpBytes(items[0].value))
+	}
+
+	prefix := commonPrefix(items, depth)
+	if prefix > 0 {
+		child := trieNode(items, depth+prefix)
+		return rlpList(rlpBytes(hexPrefix(items[0].key[depth:depth+prefix], false)), trieRef(child))
+	}
+
+	var groups [16][]trieItem
+	var value []byte
+	for _, item := range items {
+		if len(item.key) == depth {
+			value = item.value
+			continue
+		}
+		nibble := item.key[depth]
+		groups[nibble] = append(groups[nibble], item)
+	}
+	children := make([][]byte, 17)
+	for i, group := range groups {
+		if len(group) == 0 {
+			children[i] = rlpBytes(nil)
+			continue
+		}
+		children[i] = trieRef(trieNode(group, depth+1))
+	}
+	children[16] = rlpBytes(value)
+	return rlpList(children...)
+}
+
+// trieRef embeds small nodes and references larger ones by hash
+func trieRef(node []byte) []byte {
+	if len(node) < 32 {
+		return node
+	}
+	return rlpBytes(keccak256(node))
+}
+
+func commonPrefix(items []trieItem, depth int) int {
+	first := items[0].key[depth:]
+	n := len(first)
+	for _, item := range items[1:] {
+		key := item.key[depth:]
+		if len(key) < n {
+			n = len(key)
+		}
+		for i := 0; i < n; i++ {
+			if key[i] != first[i] {
+				n = i
+				break
+			}
+		}
+	}
+	return n
+}
+
+func toNibbles(b []byte) []byte {
+	nibbles := make([]byte, len(b)*2)
+	for i, v := range b {
+		nibbles[2*i] = v >> 4
+		nibbles[2*i+1] = v & 0x0f
+	}
+	return nibbles
+}
+
+// hexPrefix applies the compact hex-prefix encoding to a nibble path
+func hexPrefix(nibbles []byte, leaf bool) []byte {
+	flag := byte(0)
+	if leaf {
+		flag = 2
+	}
+	out := make([]byte, len(nibbles)/2+1)
+	if len(nibbles)%2 == 1 {
+		out[0] = (flag+1)<<4 | nibbles[0]
+		nibbles = nibbles[1:]
+	} else {
+		out[0] = flag << 4
+	}
+	for i := 0; i < len(nibbles); i += 2 {
+		out[i/2+1] = nibbles[i]<<4 | nibbles[i+1]
+	}
+	return out
+}
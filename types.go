@@ -0,0 +1,97 @@
+package engineclient
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Hash is a 32-byte value such as a block hash, state root or prevRandao
+type Hash [32]byte
+
+// Address is a 20-byte execution layer account address
+type Address [20]byte
+
+// HexToHash parses a 0x-prefixed hex string of exactly 32 bytes
+func HexToHash(s string) (Hash, error) {
+	var h Hash
+	err := decodeFixedInto(h[:], s)
+	return h, err
+}
+
+// MustHexToHash is like HexToHash but panics on malformed input, for use with
+// constants
+func MustHexToHash(s string) Hash {
+	h, err := HexToHash(s)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// HexToAddress parses a 0x-prefixed hex string of exactly 20 bytes
+func HexToAddress(s string) (Address, error) {
+	var a Address
+	err := decodeFixedInto(a[:], s)
+	return a, err
+}
+
+// MustHexToAddress is like HexToAddress but panics on malformed input, for use
+// with constants
+func MustHexToAddress(s string) Address {
+	a, err := HexToAddress(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (h Hash) Hex() string    { return encodeHex(h[:]) }
+func (h Hash) String() string { return h.Hex() }
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.Hex()), nil
+}
+
+// UnmarshalJSON accepts only a JSON string holding 0x followed by exactly 64
+// hex digits
+func (h *Hash) UnmarshalJSON(data []byte) error {
+	return unmarshalFixedJSON(h[:], data, "hash")
+}
+
+func (a Address) Hex() string    { return encodeHex(a[:]) }
+func (a Address) String() string { return a.Hex() }
+
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalJSON accepts only a JSON string holding 0x followed by exactly 40
+// hex digits
+func (a *Address) UnmarshalJSON(data []byte) error {
+	return unmarshalFixedJSON(a[:], data, "address")
+}
+
+func unmarshalFixedJSON(dst []byte, data []byte, kind string) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%s must be a JSON string, got %s", kind, data)
+	}
+	if err := decodeFixedInto(dst, s); err != nil {
+		return fmt.Errorf("invalid %s: %v", kind, err)
+	}
+	return nil
+}
+
+func decodeFixedInto(dst []byte, s string) error {
+	if len(s) < 2 || s[0] != '0' || (s[1] != 'x' && s[1] != 'X') {
+		return fmt.Errorf("hex string without 0x prefix: %q", s)
+	}
+	if len(s)-2 != 2*len(dst) {
+		return fmt.Errorf("hex string %q has %d digits, want %d", s, len(s)-2, 2*len(dst))
+	}
+	if _, err := hex.Decode(dst, []byte(s[2:])); err != nil {
+		return fmt.Errorf("invalid hex string %q: %v", s, err)
+	}
+	return nil
+}
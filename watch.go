@@ -0,0 +1,452 @@
+package engineclient
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchClientVersion is how the watch command identifies itself in
+// engine_getClientVersionV1
+var watchClientVersion = ClientVersion{Code: "EC", Name: "engine-client", Version: "dev", Commit: "0x00000000"}
+
+// SyncProgress is the EL's eth_syncing result; Syncing is false once synced
+type SyncProgress struct {
+	Syncing       bool
+	StartingBlock uint64
+	CurrentBlock  uint64
+	HighestBlock  uint64
+}
+
+// HeadBlock is the EL's latest block as seen by the watch command
+type HeadBlock struct {
+	Number    uint64
+	Hash      Hash
+	Timestamp time.Time
+}
+
+// WatchSnapshot is one poll of the EL. A field is nil when its call failed,
+// with the failure listed in Errors by the name of the failed check.
+type WatchSnapshot struct {
+	Time         time.Time
+	Duration     time.Duration
+	Clients      []ClientVersion
+	Sync         *SyncProgress
+	Head         *HeadBlock
+	Capabilities *CapabilityReport
+	Errors       map[string]error
+}
+
+// Up reports whether the EL answered anything at all
+func (s *WatchSnapshot) Up() bool {
+	return s.Clients != nil || s.Sync != nil || s.Head != nil || s.Capabilities != nil
+}
+
+// Snapshot polls the EL's client version, sync status, latest block and
+// capabilities for the given forks concurrently
+func (c *EngineClient) Snapshot(ctx context.Context, forks ...Fork) *WatchSnapshot {
+	snap := &WatchSnapshot{Time: c.clock.Now(), Errors: make(map[string]error)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	check := func(name string, f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(); err != nil {
+				mu.Lock()
+				snap.Errors[name] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	check("client", func() error {
+		response, err := c.GetClientVersion(ctx, watchClientVersion)
+		if err != nil {
+			return err
+		}
+		var clients []ClientVersion
+		if err := decodeResult(response, &clients); err != nil {
+			return err
+		}
+		mu.Lock()
+		snap.Clients = clients
+		mu.Unlock()
+		return nil
+	})
+	check("sync", func() error {
+		progress, err := c.SyncStatus(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		snap.Sync = progress
+		mu.Unlock()
+		return nil
+	})
+	check("head", func() error {
+		head, err := c.HeadBlock(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		snap.Head = head
+		mu.Unlock()
+		return nil
+	})
+	if len(forks) > 0 {
+		check("capabilities", func() error {
+			report, err := c.CheckCapabilities(ctx, forks...)
+			if report == nil {
+				return err
+			}
+			// Missing methods are part of the report, not a failed poll.
+			mu.Lock()
+			snap.Capabilities = report
+			mu.Unlock()
+			return nil
+		})
+	}
+	wg.Wait()
+	snap.Duration = c.clock.Now().Sub(snap.Time)
+	return snap
+}
+
+// SyncStatus decodes eth_syncing, which is false when the EL is synced and a
+// progress object otherwise
+func (c *EngineClient) SyncStatus(ctx context.Context) (*SyncProgress, error) {
+	response, err := c.makeRequest(ctx, "eth_syncing", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := decodeResult(response, &result); err != nil {
+		return nil, err
+	}
+	if result == false {
+		return &SyncProgress{}, nil
+	}
+	fields, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected eth_syncing result %v", result)
+	}
+	progress := &SyncProgress{Syncing: true}
+	for key, dst := range map[string]*uint64{
+		"startingBlock": &progress.StartingBlock,
+		"currentBlock":  &progress.CurrentBlock,
+		"highestBlock":  &progress.HighestBlock,
+	} {
+		if s, ok := fields[key].(string); ok {
+			if *dst, err = decodeQuantity(s); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", key, err)
+			}
+		}
+	}
+	return progress, nil
+}
+
+// HeadBlock returns the number, hash and timestamp of the EL's latest block
+func (c *EngineClient) HeadBlock(ctx context.Context) (*HeadBlock, error) {
+	response, err := c.GetBlockByNumber(ctx, "latest", false)
+	if err != nil {
+		return nil, err
+	}
+	var block *struct {
+		Number    string `json:"number"`
+		Hash      Hash   `json:"hash"`
+		Timestamp string `json:"timestamp"`
+	}
+	if err := decodeResult(response, &block); err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("EL has no latest block")
+	}
+	number, err := decodeQuantity(block.Number)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block number: %v", err)
+	}
+	timestamp, err := decodeQuantity(block.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block timestamp: %v", err)
+	}
+	return &HeadBlock{Number: number, Hash: block.Hash, Timestamp: time.Unix(int64(timestamp), 0)}, nil
+}
+
+// clientLabel names the EL as "Name Version (commit)"
+func clientLabel(clients []ClientVersion) string {
+	labels := make([]string, len(clients))
+	for i, v := range clients {
+		labels[i] = strings.TrimSpace(fmt.Sprintf("%s %s (%s)", v.Name, v.Version, v.Commit))
+	}
+	return strings.Join(labels, ", ")
+}
+
+func (p *SyncProgress) String() string {
+	if !p.Syncing {
+		return "synced"
+	}
+	if p.HighestBlock == 0 {
+		return "syncing"
+	}
+	return fmt.Sprintf("syncing %d/%d (%.1f%%)", p.CurrentBlock, p.HighestBlock, 100*float64(p.CurrentBlock)/float64(p.HighestBlock))
+}
+
+// missingMethods lists the methods a capability report lacks, in fork order
+func missingMethods(r *CapabilityReport) []string {
+	var missing []string
+	for _, fork := range forkOrder {
+		missing = append(missing, r.Missing[fork]...)
+	}
+	return missing
+}
+
+// errorNames lists the failed checks of a snapshot in a stable order
+func (s *WatchSnapshot) errorNames() []string {
+	names := make([]string, 0, len(s.Errors))
+	for name := range s.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// render draws the snapshot as the full-screen view
+func (s *WatchSnapshot) render(w io.Writer, endpoint string, interval time.Duration) {
+	fmt.Fprintf(w, "%s  every %s  %s\n\n", endpoint, interval, s.Time.Format("15:04:05"))
+	row := func(name, value string) { fmt.Fprintf(w, "%-13s %s\n", name, value) }
+	unknown := func(check string) string {
+		if err := s.Errors[check]; err != nil {
+			return "error: " + err.Error()
+		}
+		return "-"
+	}
+
+	client := unknown("client")
+	if s.Clients != nil {
+		client = clientLabel(s.Clients)
+	}
+	row("client", client)
+
+	status := unknown("sync")
+	if s.Sync != nil {
+		status = s.Sync.String()
+	}
+	row("sync", status)
+
+	head := unknown("head")
+	if s.Head != nil {
+		head = fmt.Sprintf("#%d %s  %s old", s.Head.Number, s.Head.Hash, s.Time.Sub(s.Head.Timestamp).Round(time.Second))
+	}
+	row("head", head)
+
+	if _, checked := s.Errors["capabilities"]; checked || s.Capabilities != nil {
+		caps := unknown("capabilities")
+		if s.Capabilities != nil {
+			caps = "all supported"
+			if missing := missingMethods(s.Capabilities); len(missing) > 0 {
+				caps = "missing " + strings.Join(missing, ", ")
+			}
+		}
+		row("capabilities", caps)
+	}
+	row("poll", s.Duration.Round(time.Millisecond).String())
+}
+
+// line condenses the snapshot to one line, for output that is not a terminal
+func (s *WatchSnapshot) line() string {
+	parts := []string{s.Time.Format(time.RFC3339)}
+	if s.Clients != nil {
+		parts = append(parts, clientLabel(s.Clients))
+	}
+	if s.Sync != nil {
+		parts = append(parts, s.Sync.String())
+	}
+	if s.Head != nil {
+		parts = append(parts, fmt.Sprintf("head #%d age %s", s.Head.Number, s.Time.Sub(s.Head.Timestamp).Round(time.Second)))
+	}
+	if s.Capabilities != nil {
+		if missing := missingMethods(s.Capabilities); len(missing) > 0 {
+			parts = append(parts, "missing "+strings.Join(missing, ","))
+		} else {
+			parts = append(parts, "capabilities ok")
+		}
+	}
+	for _, name := range s.errorNames() {
+		parts = append(parts, fmt.Sprintf("%s error: %v", name, s.Errors[name]))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func (s *WatchSnapshot) record() record {
+	rec := record{
+		{"time", s.Time.Format(time.RFC3339)}, {"up", s.Up()}, {"client", nil},
+		{"syncing", nil}, {"currentBlock", nil}, {"highestBlock", nil},
+		{"head", nil}, {"hash", nil}, {"age", nil},
+		{"missing", nil}, {"duration", s.Duration.Round(time.Millisecond)}, {"errors", nil},
+	}
+	if s.Clients != nil {
+		rec[2].value = clientLabel(s.Clients)
+	}
+	if s.Sync != nil {
+		rec[3].value, rec[4].value, rec[5].value = s.Sync.Syncing, s.Sync.CurrentBlock, s.Sync.HighestBlock
+	}
+	if s.Head != nil {
+		rec[6].value, rec[7].value = s.Head.Number, s.Head.Hash
+		rec[8].value = s.Time.Sub(s.Head.Timestamp).Round(time.Second)
+	}
+	if s.Capabilities != nil {
+		rec[9].value = missingMethods(s.Capabilities)
+	}
+	if len(s.Errors) > 0 {
+		errs := make(map[string]string, len(s.Errors))
+		for name, err := range s.Errors {
+			errs[name] = err.Error()
+		}
+		rec[11].value = errs
+	}
+	return rec
+}
+
+// writeMetrics renders the snapshot in the Prometheus text format, for the
+// node exporter's textfile collector. polls and failures count every poll
+// and every poll with at least one failed check since the command started.
+func (s *WatchSnapshot) writeMetrics(w io.Writer, endpoint string, polls, failures uint64) {
+	label := fmt.Sprintf("{endpoint=%q}", endpoint)
+	gauge := func(name, help string, value interface{}) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s%s %v\n", name, help, name, name, label, value)
+	}
+	counter := func(name, help string, value uint64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s%s %d\n", name, help, name, name, label, value)
+	}
+	boolValue := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	gauge("engine_watch_up", "Whether the EL answered the last poll.", boolValue(s.Up()))
+	gauge("engine_watch_poll_duration_seconds", "Duration of the last poll.", s.Duration.Seconds())
+	gauge("engine_watch_last_poll_timestamp_seconds", "Time of the last poll.", s.Time.Unix())
+	counter("engine_watch_polls_total", "Polls made.", polls)
+	counter("engine_watch_poll_failures_total", "Polls with at least one failed check.", failures)
+	if len(s.Clients) > 0 {
+		v := s.Clients[0]
+		fmt.Fprintf(w, "# HELP engine_watch_client_info The EL's client version.\n# TYPE engine_watch_client_info gauge\n")
+		fmt.Fprintf(w, "engine_watch_client_info{endpoint=%q,code=%q,name=%q,version=%q,commit=%q} 1\n",
+			endpoint, v.Code, v.Name, v.Version, v.Commit)
+	}
+	if s.Sync != nil {
+		gauge("engine_watch_syncing", "Whether the EL reports itself syncing.", boolValue(s.Sync.Syncing))
+		if s.Sync.Syncing {
+			gauge("engine_watch_sync_current_block", "Block the EL has synced to.", s.Sync.CurrentBlock)
+			gauge("engine_watch_sync_highest_block", "Highest block the EL knows of.", s.Sync.HighestBlock)
+		}
+	}
+	if s.Head != nil {
+		gauge("engine_watch_head_block", "Number of the EL's latest block.", s.Head.Number)
+		gauge("engine_watch_head_timestamp_seconds", "Timestamp of the EL's latest block.", s.Head.Timestamp.Unix())
+	}
+	if s.Capabilities != nil {
+		gauge("engine_watch_missing_capabilities", "Engine methods the EL lacks for the watched forks.", len(missingMethods(s.Capabilities)))
+	}
+}
+
+// isTerminal reports whether f is a character device, so the live view is
+// only drawn where it can be redrawn in place
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runWatch polls the EL on an interval, redrawing a live view on a terminal
+// and printing a line or record per poll otherwise
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	config := addConfigFlags(fs)
+	interval := fs.Duration("interval", 5*time.Second, "time between polls")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout for each poll")
+	forkList := fs.String("forks", "paris,shanghai,cancun,prague", "comma-separated forks whose methods to check (empty to skip)")
+	metricsPath := fs.String("metrics", "", "write Prometheus metrics to this file after every poll")
+	count := fs.Int("count", 0, "stop after this many polls (0 to run until interrupted)")
+	output := addOutputFlag(fs)
+	fs.Parse(args)
+
+	out, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive")
+	}
+	var forks []Fork
+	if *forkList != "" {
+		for _, name := range strings.Split(*forkList, ",") {
+			fork, err := ParseFork(name)
+			if err != nil {
+				return err
+			}
+			forks = append(forks, fork)
+		}
+	}
+	cfg, err := config.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	live := out.format == outputText && isTerminal(os.Stdout)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	var polls, failures uint64
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		snap := client.Snapshot(ctx, forks...)
+		cancel()
+		polls++
+		if len(snap.Errors) > 0 {
+			failures++
+		}
+
+		out.emit(snap.record(), func(w io.Writer) {
+			if !live {
+				fmt.Fprintln(w, snap.line())
+				return
+			}
+			var buf bytes.Buffer
+			// Clear the screen and draw from the top-left corner.
+			buf.WriteString("\x1b[H\x1b[2J")
+			snap.render(&buf, cfg.Endpoint, *interval)
+			w.Write(buf.Bytes())
+		})
+		if *metricsPath != "" {
+			var buf bytes.Buffer
+			snap.writeMetrics(&buf, cfg.Endpoint, polls, failures)
+			if err := writeFileAtomic(*metricsPath, buf.Bytes()); err != nil {
+				return fmt.Errorf("failed to write metrics: %v", err)
+			}
+			// The temp file is private; the collector may run as another user.
+			os.Chmod(*metricsPath, 0o644)
+		}
+
+		if *count > 0 && polls >= uint64(*count) {
+			if !snap.Up() {
+				return fmt.Errorf("EL did not answer")
+			}
+			return nil
+		}
+		<-ticker.C
+	}
+}
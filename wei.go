@@ -0,0 +1,180 @@
+package engineclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	weiPerGwei  = big.NewInt(1e9)
+	weiPerEther = big.NewInt(1e18)
+)
+
+// Wei is an amount of wei encoded as a hex quantity, such as the blockValue
+// of a getPayload envelope
+type Wei struct {
+	big.Int
+}
+
+func (w *Wei) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("wei amount must be a hex string: %v", err)
+	}
+	v, err := decodeBigQuantity(s)
+	if err != nil {
+		return err
+	}
+	w.Set(v)
+	return nil
+}
+
+func (w *Wei) MarshalJSON() ([]byte, error) {
+	return json.Marshal("0x" + w.Text(16))
+}
+
+// Gwei formats the amount in gwei without rounding
+func (w *Wei) Gwei() string {
+	return formatUnits(&w.Int, weiPerGwei)
+}
+
+// Ether formats the amount in ether without rounding
+func (w *Wei) Ether() string {
+	return formatUnits(&w.Int, weiPerEther)
+}
+
+// FormatGwei formats a wei amount in gwei, for values held as *big.Int
+func FormatGwei(v *big.Int) string {
+	return formatUnits(v, weiPerGwei)
+}
+
+// FormatEther formats a wei amount in ether, for values held as *big.Int
+func FormatEther(v *big.Int) string {
+	return formatUnits(v, weiPerEther)
+}
+
+// formatUnits renders v/unit as an exact decimal with trailing zeros trimmed
+func formatUnits(v, unit *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	q, r := new(big.Int).QuoRem(new(big.Int).Abs(v), unit, new(big.Int))
+	s := q.String()
+	if r.Sign() != 0 {
+		digits := len(unit.String()) - 1
+		frac := fmt.Sprintf("%0*s", digits, r.String())
+		s += "." + strings.TrimRight(frac, "0")
+	}
+	if v.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// blockValueOf extracts the blockValue of a getPayload response. V1
+// responses carry no value and report false.
+func blockValueOf(response map[string]interface{}) (*big.Int, bool) {
+	result, _ := response["result"].(map[string]interface{})
+	s, _ := result["blockValue"].(string)
+	if s == "" {
+		return nil, false
+	}
+	v, err := decodeBigQuantity(s)
+	return v, err == nil
+}
+
+// BlockValueSample is one blockValue seen by a BlockValueTracker
+type BlockValueSample struct {
+	Time     time.Time
+	Endpoint string
+	Value    *big.Int
+}
+
+// BlockValueSummary describes the samples a tracker holds
+type BlockValueSummary struct {
+	Count  int
+	Min    *big.Int
+	Max    *big.Int
+	Mean   *big.Int
+	Median *big.Int
+	Last   *big.Int
+}
+
+func (s BlockValueSummary) String() string {
+	if s.Count == 0 {
+		return "no payloads"
+	}
+	return fmt.Sprintf("n=%d last=%s min=%s median=%s mean=%s max=%s ETH",
+		s.Count, FormatEther(s.Last), FormatEther(s.Min), FormatEther(s.Median), FormatEther(s.Mean), FormatEther(s.Max))
+}
+
+// BlockValueTracker keeps the most recent payload values so a builder can
+// compare what its EL bids over time. It is safe for concurrent use.
+type BlockValueTracker struct {
+	mu      sync.Mutex
+	size    int
+	samples []BlockValueSample
+}
+
+// NewBlockValueTracker keeps up to size samples, dropping the oldest first
+func NewBlockValueTracker(size int) *BlockValueTracker {
+	if size < 1 {
+		size = 1
+	}
+	return &BlockValueTracker{size: size}
+}
+
+// Add records a value seen from endpoint at the given time
+func (t *BlockValueTracker) Add(at time.Time, endpoint string, value *big.Int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) == t.size {
+		t.samples = append(t.samples[:0], t.samples[1:]...)
+	}
+	t.samples = append(t.samples, BlockValueSample{Time: at, Endpoint: endpoint, Value: new(big.Int).Set(value)})
+}
+
+// Samples returns the held samples, oldest first
+func (t *BlockValueTracker) Samples() []BlockValueSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]BlockValueSample, len(t.samples))
+	copy(out, t.samples)
+	return out
+}
+
+// Summary computes statistics over the held samples, optionally only those
+// since the given time; a zero time includes everything
+func (t *BlockValueTracker) Summary(since time.Time) BlockValueSummary {
+	var values []*big.Int
+	for _, s := range t.Samples() {
+		if s.Time.Before(since) {
+			continue
+		}
+		values = append(values, s.Value)
+	}
+	summary := BlockValueSummary{Count: len(values)}
+	if len(values) == 0 {
+		return summary
+	}
+	summary.Last = values[len(values)-1]
+
+	sorted := make([]*big.Int, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+	summary.Min = sorted[0]
+	summary.Max = sorted[len(sorted)-1]
+	summary.Median = sorted[len(sorted)/2]
+
+	total := new(big.Int)
+	for _, v := range values {
+		total.Add(total, v)
+	}
+	summary.Mean = total.Quo(total, big.NewInt(int64(len(values))))
+	return summary
+}